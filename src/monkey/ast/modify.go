@@ -15,11 +15,35 @@ func Modify(node Node, modifier ModifierFunc) Node {
 	case *InfixExpression:
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
+	case *AssignExpression:
+		if _, ok := node.Target.(*IndexExpression); ok {
+			node.Target, _ = Modify(node.Target, modifier).(Expression)
+		}
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *RangeExpression:
+		node.Start, _ = Modify(node.Start, modifier).(Expression)
+		node.Stop, _ = Modify(node.Stop, modifier).(Expression)
+		if node.Step != nil {
+			node.Step, _ = Modify(node.Step, modifier).(Expression)
+		}
+	case *CallExpression:
+		node.Function, _ = Modify(node.Function, modifier).(Expression)
+		for i := range node.Arguments {
+			node.Arguments[i], _ = Modify(node.Arguments[i], modifier).(Expression)
+		}
 	case *PrefixExpression:
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
 	case *IndexExpression:
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Index, _ = Modify(node.Index, modifier).(Expression)
+	case *SliceExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+		if node.Low != nil {
+			node.Low, _ = Modify(node.Low, modifier).(Expression)
+		}
+		if node.High != nil {
+			node.High, _ = Modify(node.High, modifier).(Expression)
+		}
 	case *IfExpression:
 		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
 		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
@@ -32,25 +56,45 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		}
 	case *ReturnStatement:
 		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
+	case *ForStatement:
+		node.Iterable, _ = Modify(node.Iterable, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *WhileStatement:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
 	case *LetStatement:
 		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *NamedArgument:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	case *FunctionLiteral:
 		for i := range node.Parameters {
 			node.Parameters[i], _ = Modify(node.Parameters[i], modifier).(*Identifier)
 		}
+		for name, def := range node.Defaults {
+			node.Defaults[name], _ = Modify(def, modifier).(Expression)
+		}
 		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *InterpolatedString:
+		for i := range node.Expressions {
+			node.Expressions[i], _ = Modify(node.Expressions[i], modifier).(Expression)
+		}
 	case *ArrayLiteral:
 		for i := range node.Elements {
 			node.Elements[i], _ = Modify(node.Elements[i], modifier).(Expression)
 		}
 	case *HashLiteral:
 		newPairs := make(map[Expression]Expression)
+		newKeys := make(map[Expression]Expression)
 		for key, value := range node.Pairs {
 			newKey := Modify(key, modifier).(Expression)
 			newValue := Modify(value, modifier).(Expression)
 			newPairs[newKey] = newValue
+			newKeys[key] = newKey
 		}
 		node.Pairs = newPairs
+		for i, key := range node.Keys {
+			node.Keys[i] = newKeys[key]
+		}
 	}
 
 	return modifier(node)
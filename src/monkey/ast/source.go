@@ -0,0 +1,93 @@
+package ast
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"monkey/token"
+)
+
+// Source returns the text of the program rebuilt from its tokens. When the
+// program was parsed from a lexer in lexer.ScanTrivia mode this is identical
+// to the input, byte for byte.
+func (p *Program) Source() string {
+	var out strings.Builder
+
+	for _, tok := range p.Tokens {
+		writeToken(&out, tok)
+	}
+
+	return out.String()
+}
+
+// NodeSource returns the text of node as it appears in the source of the
+// program, including the leading trivia of its first token and the trailing
+// trivia of its last.
+func (p *Program) NodeSource(node Node) string {
+	first, last, ok := p.tokenRange(node)
+	if !ok {
+		return ""
+	}
+
+	var out strings.Builder
+
+	for _, tok := range p.Tokens[first : last+1] {
+		writeToken(&out, tok)
+	}
+
+	return out.String()
+}
+
+// Replace returns the source of the program with the text of node replaced
+// by text. Everything outside the node, including the trivia around it, is
+// kept as it was.
+func (p *Program) Replace(node Node, text string) (string, error) {
+	first, last, ok := p.tokenRange(node)
+	if !ok {
+		return "", errors.New("node is not part of the program source")
+	}
+
+	var out strings.Builder
+
+	for _, tok := range p.Tokens[:first] {
+		writeToken(&out, tok)
+	}
+
+	out.WriteString(p.Tokens[first].Leading)
+	out.WriteString(text)
+	out.WriteString(p.Tokens[last].Trailing)
+
+	for _, tok := range p.Tokens[last+1:] {
+		writeToken(&out, tok)
+	}
+
+	return out.String(), nil
+}
+
+// tokenRange returns the indexes of the first and last token of node.
+func (p *Program) tokenRange(node Node) (first, last int, ok bool) {
+	pos, end := node.Pos(), node.End()
+	if !pos.IsValid() || !end.IsValid() {
+		return 0, 0, false
+	}
+
+	first = sort.Search(len(p.Tokens), func(i int) bool {
+		return p.Tokens[i].Pos.Offset >= pos.Offset
+	})
+	last = sort.Search(len(p.Tokens), func(i int) bool {
+		return p.Tokens[i].End.Offset > end.Offset
+	}) - 1
+
+	if first >= len(p.Tokens) || last < first {
+		return 0, 0, false
+	}
+
+	return first, last, true
+}
+
+func writeToken(out *strings.Builder, tok token.Token) {
+	out.WriteString(tok.Leading)
+	out.WriteString(tok.Raw)
+	out.WriteString(tok.Trailing)
+}
@@ -0,0 +1,236 @@
+// Package diagnostic renders lexer, parser and evaluator errors with the
+// line of source they point at, in the style of rustc and clang:
+//
+//	error: expected next token to be IDENT, got = instead
+//	 --> test.mk:2:5
+//	  |
+//	2 | let = 10;
+//	  |     ^
+//	  = help: a name was expected here
+package diagnostic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"monkey/object"
+	"monkey/parser"
+	"monkey/token"
+)
+
+// Diagnostic is an error message tied to a span of source.
+type Diagnostic struct {
+	Pos     token.Position // start of the span, if known
+	End     token.Position // end of the span; the span is one column if not valid
+	Message string
+	Help    string // optional note on how to fix the error
+}
+
+// FromParseError returns the diagnostic for a lexer or parser error,
+// spanning the token it was found at.
+func FromParseError(err *parser.ParseError) Diagnostic {
+	d := Diagnostic{
+		Pos:     err.Pos,
+		End:     err.Found.End,
+		Message: err.Msg,
+	}
+
+	switch err.Code {
+	case parser.UnexpectedToken:
+		if len(err.Expected) == 1 && err.Expected[0] == token.IDENT {
+			d.Help = "a name was expected here"
+		}
+	case parser.NoPrefixParseFn:
+		d.Help = "an expression cannot start with " + string(err.Found.Type)
+	case parser.IntegerOverflow:
+		d.Help = "integers must fit in 64 bits; use a float for larger numbers"
+	}
+
+	return d
+}
+
+// FromError returns the diagnostic for an evaluator error, spanning the
+// node that produced it.
+func FromError(err *object.Error) Diagnostic {
+	return Diagnostic{
+		Pos:     err.Pos,
+		End:     err.End,
+		Message: err.Message,
+	}
+}
+
+const (
+	bold  = "\x1b[1m"
+	red   = "\x1b[1;31m"
+	blue  = "\x1b[1;34m"
+	reset = "\x1b[0m"
+)
+
+// LineFunc returns line n of a source, counting from 1, without its line
+// break. It reports false if the source has no such line.
+type LineFunc func(n int) (string, bool)
+
+// StringLines returns a LineFunc for the lines of source.
+func StringLines(source string) LineFunc {
+	return func(n int) (string, bool) { return sourceLine(source, n) }
+}
+
+// FileLines returns a LineFunc that reads the named file again for each
+// line it is asked for, so the source need not be kept in memory for the
+// rare case that an error is reported.
+func FileLines(name string) LineFunc {
+	return func(n int) (string, bool) {
+		f, err := os.Open(name)
+		if err != nil {
+			return "", false
+		}
+		defer f.Close()
+
+		r := bufio.NewReader(f)
+		for i := 1; ; i++ {
+			line, err := r.ReadString('\n')
+			if i == n {
+				if err != nil && err != io.EOF {
+					return "", false
+				}
+				line = strings.TrimSuffix(line, "\n")
+				return strings.TrimSuffix(line, "\r"), true
+			}
+			if err != nil {
+				return "", false
+			}
+		}
+	}
+}
+
+// Render formats d, quoting the line of source it points at when source
+// contains it. ANSI color codes are added when color is true.
+func Render(d Diagnostic, source string, color bool) string {
+	return RenderLines(d, StringLines(source), color)
+}
+
+// RenderLines is like Render, but looks up the line to quote with lines.
+func RenderLines(d Diagnostic, lines LineFunc, color bool) string {
+	style := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + reset
+	}
+
+	var out strings.Builder
+
+	out.WriteString(style(red, "error") + style(bold, ": "+d.Message) + "\n")
+
+	if !d.Pos.IsValid() {
+		if d.Help != "" {
+			out.WriteString(style(bold, "help") + ": " + d.Help + "\n")
+		}
+		return out.String()
+	}
+
+	line, ok := lines(d.Pos.Line)
+	gutter := strings.Repeat(" ", len(strconv.Itoa(d.Pos.Line)))
+
+	fmt.Fprintf(&out, "%s%s %s\n", gutter, style(blue, "-->"), d.Pos)
+
+	if ok {
+		out.WriteString(gutter + style(blue, " |") + "\n")
+		out.WriteString(style(blue, strconv.Itoa(d.Pos.Line)+" |") + " " + line + "\n")
+		out.WriteString(gutter + style(blue, " |") + " " +
+			indent(line, d.Pos.Column) + style(red, underline(d, line)) + "\n")
+	}
+
+	if d.Help != "" {
+		out.WriteString(gutter + style(blue, " =") + " " + style(bold, "help") + ": " + d.Help + "\n")
+	}
+
+	return out.String()
+}
+
+// Fprint writes the rendering of d to w.
+func Fprint(w io.Writer, d Diagnostic, source string, color bool) {
+	io.WriteString(w, Render(d, source, color))
+}
+
+// FprintLines writes the rendering of d to w, looking up the line to quote
+// with lines.
+func FprintLines(w io.Writer, d Diagnostic, lines LineFunc, color bool) {
+	io.WriteString(w, RenderLines(d, lines, color))
+}
+
+// ColorEnabled reports whether output to w should be colored: w must be a
+// terminal and the NO_COLOR environment variable must not be set.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// sourceLine returns line n of source, counting from 1, without its line
+// break.
+func sourceLine(source string, n int) (string, bool) {
+	for i := 1; i < n; i++ {
+		nl := strings.IndexByte(source, '\n')
+		if nl < 0 {
+			return "", false
+		}
+		source = source[nl+1:]
+	}
+
+	if nl := strings.IndexByte(source, '\n'); nl >= 0 {
+		source = source[:nl]
+	}
+
+	return strings.TrimSuffix(source, "\r"), true
+}
+
+// indent returns the padding that lines up with column of line, keeping
+// its tabs so the underline stays aligned.
+func indent(line string, column int) string {
+	var out strings.Builder
+
+	for _, r := range line {
+		if column--; column <= 0 {
+			break
+		}
+		if r == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+	}
+
+	return out.String()
+}
+
+// underline returns the carets under the span of d, which is cut off at the
+// end of its first line.
+func underline(d Diagnostic, line string) string {
+	width := 1
+
+	if d.End.IsValid() {
+		end := d.End.Column
+		if d.End.Line != d.Pos.Line {
+			end = utf8.RuneCountInString(line) + 1
+		}
+		if end-d.Pos.Column > width {
+			width = end - d.Pos.Column
+		}
+	}
+
+	return strings.Repeat("^", width)
+}
@@ -0,0 +1,215 @@
+package diagnostic
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/token"
+)
+
+func TestRender(t *testing.T) {
+	source := "let x = 5;\n\tlet = 10;\nlet s = \"héllo\" + 1;\r\nlet f = fn() {\n  1\n};"
+
+	tests := []struct {
+		diagnostic Diagnostic
+		expected   string
+	}{
+		{
+			Diagnostic{
+				Pos:     token.Position{Filename: "test.mk", Line: 2, Column: 6},
+				End:     token.Position{Filename: "test.mk", Line: 2, Column: 7},
+				Message: "expected next token to be IDENT, got = instead",
+				Help:    "a name was expected here",
+			},
+			`error: expected next token to be IDENT, got = instead
+ --> test.mk:2:6
+  |
+2 | 	let = 10;
+  | 	    ^
+  = help: a name was expected here
+`,
+		},
+		{
+			Diagnostic{
+				Pos:     token.Position{Line: 3, Column: 9},
+				End:     token.Position{Line: 3, Column: 20},
+				Message: "type mismatch: STRING + INTEGER",
+			},
+			`error: type mismatch: STRING + INTEGER
+ --> 3:9
+  |
+3 | let s = "héllo" + 1;
+  |         ^^^^^^^^^^^
+`,
+		},
+		{
+			Diagnostic{
+				Pos:     token.Position{Line: 4, Column: 9},
+				End:     token.Position{Line: 6, Column: 2},
+				Message: "spans lines",
+			},
+			`error: spans lines
+ --> 4:9
+  |
+4 | let f = fn() {
+  |         ^^^^^^
+`,
+		},
+		{
+			Diagnostic{
+				Pos:     token.Position{Line: 40, Column: 1},
+				Message: "not in source",
+			},
+			`error: not in source
+  --> 40:1
+`,
+		},
+		{
+			Diagnostic{Message: "no position", Help: "try again"},
+			`error: no position
+help: try again
+`,
+		},
+	}
+
+	for i, tt := range tests {
+		got := Render(tt.diagnostic, source, false)
+		if got != tt.expected {
+			t.Errorf("tests[%d] - wrong rendering.\nexpected=\n%s\ngot=\n%s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestRenderColor(t *testing.T) {
+	d := Diagnostic{
+		Pos:     token.Position{Line: 1, Column: 5},
+		End:     token.Position{Line: 1, Column: 6},
+		Message: "oops",
+	}
+
+	expected := "\x1b[1;31merror\x1b[0m\x1b[1m: oops\x1b[0m\n" +
+		" \x1b[1;34m-->\x1b[0m 1:5\n" +
+		" \x1b[1;34m |\x1b[0m\n" +
+		"\x1b[1;34m1 |\x1b[0m let = 1;\n" +
+		" \x1b[1;34m |\x1b[0m     \x1b[1;31m^\x1b[0m\n"
+
+	if got := Render(d, "let = 1;", true); got != expected {
+		t.Errorf("wrong rendering.\nexpected=%q\ngot=     %q", expected, got)
+	}
+}
+
+func TestFromParseError(t *testing.T) {
+	source := "let x = 5 @ 3;\nlet 1 = 2;\nlet z = * 2;"
+
+	l := lexer.NewFile("test.mk", source, 0)
+	p := parser.New(l)
+	p.ParseProgram()
+
+	expected := []string{
+		`error: unrecognized character in action: U+0040 '@'
+ --> test.mk:1:11
+  |
+1 | let x = 5 @ 3;
+  |           ^
+`,
+		`error: expected next token to be IDENT, got INT instead
+ --> test.mk:2:5
+  |
+2 | let 1 = 2;
+  |     ^
+  = help: a name was expected here
+`,
+		`error: no prefix parse function for * found
+ --> test.mk:3:9
+  |
+3 | let z = * 2;
+  |         ^
+  = help: an expression cannot start with *
+`,
+	}
+
+	errors := p.ParseErrors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. want=%d, got=%d: %q",
+			len(expected), len(errors), p.Errors())
+	}
+
+	for i, err := range errors {
+		var out bytes.Buffer
+		Fprint(&out, FromParseError(err), source, false)
+
+		if out.String() != expected[i] {
+			t.Errorf("errors[%d] - wrong rendering.\nexpected=\n%s\ngot=\n%s",
+				i, expected[i], out.String())
+		}
+	}
+}
+
+func TestFromError(t *testing.T) {
+	source := "let f = fn(a) {\n  a + true\n};\nf(1);"
+
+	l := lexer.New(source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %q", p.Errors())
+	}
+
+	err, ok := evaluator.Eval(program, object.NewEnvironment()).(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error")
+	}
+
+	expected := `error: type mismatch: INTEGER + BOOLEAN
+ --> 2:3
+  |
+2 |   a + true
+  |   ^^^^^^^^
+`
+
+	got := Render(FromError(err), source, false)
+	if got != expected {
+		t.Errorf("wrong rendering.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("rendering without color should not contain escape codes")
+	}
+}
+
+func TestFileLines(t *testing.T) {
+	sources := []string{
+		"",
+		"let a = 1;",
+		"let a = 1;\nlet b = 2;\n",
+		"let a = 1;\r\n\r\nlet b = a + true;",
+	}
+
+	for _, source := range sources {
+		name := filepath.Join(t.TempDir(), "test.mk")
+		if err := os.WriteFile(name, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		fromFile, fromString := FileLines(name), StringLines(source)
+		for n := 1; n <= 5; n++ {
+			fileLine, fileOK := fromFile(n)
+			stringLine, stringOK := fromString(n)
+			if fileLine != stringLine || fileOK != stringOK {
+				t.Errorf("%q: line %d from file is (%q, %t), from string (%q, %t)",
+					source, n, fileLine, fileOK, stringLine, stringOK)
+			}
+		}
+	}
+
+	if _, ok := FileLines(filepath.Join(t.TempDir(), "missing.mk"))(1); ok {
+		t.Errorf("a missing file should have no lines")
+	}
+}
@@ -2,12 +2,18 @@ package evaluator
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"monkey/object"
 )
 
+// maxArrayLen is the most elements `array` will make from a range, so that a
+// huge range gives an error instead of exhausting memory.
+const maxArrayLen = 1 << 26
+
 var builtins = map[string]*object.Builtin{
 	"len": {
+		Params: []string{"value"},
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -18,7 +24,9 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Range:
+				return &object.Integer{Value: arg.Len()}
 			default:
 				return newError("argument to `len` not supported, got %s",
 					args[0].Type())
@@ -35,6 +43,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"first": {
+		Params: []string{"array"},
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -54,6 +63,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": {
+		Params: []string{"array"},
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -74,6 +84,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"rest": {
+		Params: []string{"array"},
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1",
@@ -96,6 +107,7 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": {
+		Params: []string{"array", "value"},
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
 				return newError("wrong number of arguments. got=%d, want=2",
@@ -116,4 +128,68 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+	// range takes no named arguments: with one argument, that is the end
+	"range": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=1 to 3",
+					len(args))
+			}
+
+			bounds := make([]int64, len(args))
+			for i, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("arguments to `range` must be INTEGER, got %s",
+						arg.Type())
+				}
+				bounds[i] = integer.Value
+			}
+
+			switch len(bounds) {
+			case 1:
+				return newRange(0, bounds[0], 1, false)
+			case 2:
+				return newRange(bounds[0], bounds[1], 1, false)
+			default:
+				return newRange(bounds[0], bounds[1], bounds[2], false)
+			}
+		},
+	},
+	"array": {
+		Params: []string{"value"},
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			var elements []object.Object
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				elements = make([]object.Object, len(arg.Elements))
+				copy(elements, arg.Elements)
+			case *object.Range:
+				n := arg.Len()
+				if n < 0 || n > maxArrayLen {
+					return newError("range too long for `array`: %s", arg.Inspect())
+				}
+				elements = make([]object.Object, n)
+				for i := range elements {
+					elements[i] = &object.Integer{Value: arg.At(int64(i))}
+				}
+			case *object.String:
+				elements = []object.Object{}
+				for _, r := range arg.Value {
+					elements = append(elements, &object.String{Value: string(r)})
+				}
+			default:
+				return newError("argument to `array` not supported, got %s",
+					args[0].Type())
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
 }
@@ -2,18 +2,35 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 
 	"monkey/ast"
 	"monkey/object"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// the innermost node that produced an error gives its position
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+		err.End = node.End()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
@@ -28,48 +45,82 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isSignal(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isSignal(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Pattern != nil {
+			if err := bindPattern(node.Pattern, val, env); err != nil {
+				return err
+			}
+		} else {
+			env.Set(node.Name.Value, val)
+		}
 
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isSignal(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isSignal(left) {
 			return left
 		}
 
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isSignal(right) {
 			return right
 		}
 
 		return evalInfixExpression(node.Operator, left, right)
 
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
+	case *ast.RangeExpression:
+		return evalRangeExpression(node, env)
+
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
@@ -79,7 +130,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{
+			Parameters: params,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Env:        env,
+			Body:       body,
+		}
 
 	case *ast.CallExpression:
 		if node.Function.TokenLiteral() == "quote" {
@@ -87,35 +144,45 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isSignal(function) {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		positional, named := splitArguments(node.Arguments)
+
+		args := evalExpressions(positional, env)
+		if len(args) == 1 && isSignal(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		namedArgs, signal := evalNamedArguments(named, env)
+		if signal != nil {
+			return signal
+		}
+
+		return applyFunction(function, args, namedArgs)
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isSignal(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isSignal(left) {
 			return left
 		}
 		index := Eval(node.Index, env)
-		if isError(index) {
+		if isSignal(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
 
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 
@@ -151,8 +218,9 @@ func evalBlockStatement(
 		result = Eval(statement, env)
 
 		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ,
+				object.BREAK_OBJ, object.CONTINUE_OBJ:
 				return result
 			}
 		}
@@ -161,6 +229,205 @@ func evalBlockStatement(
 	return result
 }
 
+func evalWhileStatement(
+	node *ast.WhileStatement,
+	env *object.Environment,
+) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isSignal(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		if result, stop := loopSignal(Eval(node.Body, env)); stop {
+			return result
+		}
+	}
+}
+
+// bindPattern takes val apart according to pattern and binds each name in
+// env. The error, if any, points at the part of the pattern that failed.
+func bindPattern(pattern ast.Pattern, val object.Object, env *object.Environment) *object.Error {
+	var err *object.Error
+
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		env.Set(pattern.Value, val)
+
+	case *ast.ArrayPattern:
+		err = bindArrayPattern(pattern, val, env)
+
+	case *ast.HashPattern:
+		err = bindHashPattern(pattern, val, env)
+	}
+
+	if err != nil && !err.Pos.IsValid() {
+		err.Pos = pattern.Pos()
+		err.End = pattern.End()
+	}
+
+	return err
+}
+
+func bindArrayPattern(pattern *ast.ArrayPattern, val object.Object, env *object.Environment) *object.Error {
+	array, ok := val.(*object.Array)
+	if !ok {
+		return newError("cannot destructure %s as an array", val.Type())
+	}
+
+	want, got := len(pattern.Elements), len(array.Elements)
+	switch {
+	case pattern.Rest == nil && got != want:
+		return newError("array pattern expects %d elements, got %d", want, got)
+	case pattern.Rest != nil && got < want:
+		return newError("array pattern expects at least %d elements, got %d", want, got)
+	}
+
+	for i, el := range pattern.Elements {
+		if err := bindPattern(el, array.Elements[i], env); err != nil {
+			return err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := make([]object.Object, got-want)
+		copy(rest, array.Elements[want:])
+		env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return nil
+}
+
+func bindHashPattern(pattern *ast.HashPattern, val object.Object, env *object.Environment) *object.Error {
+	hash, ok := val.(*object.Hash)
+	if !ok {
+		return newError("cannot destructure %s as a hash", val.Type())
+	}
+
+	for _, pair := range pattern.Pairs {
+		key := &object.String{Value: pair.Key.Value}
+
+		entry, ok := hash.Pairs[key.HashKey()]
+		if !ok {
+			err := newError("missing key in hash pattern: %s", pair.Key.Value)
+			err.Pos = pair.Key.Pos()
+			err.End = pair.Key.End()
+			return err
+		}
+
+		if err := bindPattern(pair.Value, entry.Value, env); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func evalForStatement(
+	node *ast.ForStatement,
+	env *object.Environment,
+) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isSignal(iterable) {
+		return iterable
+	}
+
+	_, isHash := iterable.(*object.Hash)
+
+	var result object.Object = NULL
+
+	err := iterate(iterable, func(key, value object.Object) bool {
+		// a fresh scope each time, so closures see this iteration's values
+		loopEnv := object.NewEnclosedEnvironment(env)
+
+		switch {
+		case node.Key != nil:
+			loopEnv.Set(node.Key.Value, key)
+			loopEnv.Set(node.Value.Value, value)
+		case isHash:
+			loopEnv.Set(node.Value.Value, key)
+		default:
+			loopEnv.Set(node.Value.Value, value)
+		}
+
+		signal, stop := loopSignal(Eval(node.Body, loopEnv))
+		if stop {
+			result = signal
+		}
+		return !stop
+	})
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+// iterate calls fn with the index and element of each item of iterable, or
+// the key and value of each pair of a hash, until fn returns false.
+func iterate(iterable object.Object, fn func(key, value object.Object) bool) *object.Error {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		// the body may change the array, so check the length each time
+		for i := 0; i < len(iterable.Elements); i++ {
+			if !fn(&object.Integer{Value: int64(i)}, iterable.Elements[i]) {
+				break
+			}
+		}
+
+	case *object.Hash:
+		for _, key := range iterable.Order {
+			pair := iterable.Pairs[key]
+			if !fn(pair.Key, pair.Value) {
+				break
+			}
+		}
+
+	case *object.String:
+		i := 0
+		for _, r := range iterable.Value {
+			if !fn(&object.Integer{Value: int64(i)}, &object.String{Value: string(r)}) {
+				break
+			}
+			i++
+		}
+
+	case *object.Range:
+		for i := int64(0); i < iterable.Len(); i++ {
+			if !fn(&object.Integer{Value: i}, &object.Integer{Value: iterable.At(i)}) {
+				break
+			}
+		}
+
+	default:
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	return nil
+}
+
+// loopSignal interprets the result of running a loop body once. It reports
+// whether the loop should stop and, if so, the loop's result.
+func loopSignal(result object.Object) (object.Object, bool) {
+	if result == BREAK {
+		return NULL, true
+	}
+
+	// a continue needs nothing more than starting the next iteration
+	if result != nil {
+		rt := result.Type()
+		if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -174,6 +441,8 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -186,6 +455,8 @@ func evalInfixExpression(
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
@@ -201,6 +472,32 @@ func evalInfixExpression(
 	}
 }
 
+// evalLogicalExpression evaluates && and ||, which only evaluate their right
+// operand when the left one does not decide the result.
+func evalLogicalExpression(
+	node *ast.InfixExpression,
+	env *object.Environment,
+) object.Object {
+	left := Eval(node.Left, env)
+	if isSignal(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(node.Right, env)
+	if isSignal(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
 	case TRUE:
@@ -215,12 +512,23 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: -right.Value}
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
+}
+
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	if right.Type() != object.INTEGER_OBJ {
+		return newError("unknown operator: ~%s", right.Type())
+	}
 
 	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	return &object.Integer{Value: ^value}
 }
 
 func evalIntegerInfixExpression(
@@ -238,11 +546,39 @@ func evalIntegerInfixExpression(
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal << rightVal}
+	case ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
@@ -253,6 +589,55 @@ func evalIntegerInfixExpression(
 	}
 }
 
+// evalFloatInfixExpression handles two numbers of which at least one is a
+// float; an integer operand is promoted to float.
+func evalFloatInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
 func evalStringInfixExpression(
 	operator string,
 	left, right object.Object,
@@ -267,12 +652,33 @@ func evalStringInfixExpression(
 	return &object.String{Value: leftVal + rightVal}
 }
 
+func evalInterpolatedString(
+	node *ast.InterpolatedString,
+	env *object.Environment,
+) object.Object {
+	var out strings.Builder
+
+	out.WriteString(node.Strings[0])
+
+	for i, exp := range node.Expressions {
+		value := Eval(exp, env)
+		if isSignal(value) {
+			return value
+		}
+
+		out.WriteString(value.Inspect())
+		out.WriteString(node.Strings[i+1])
+	}
+
+	return &object.String{Value: out.String()}
+}
+
 func evalIfExpression(
 	ie *ast.IfExpression,
 	env *object.Environment,
 ) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	if isSignal(condition) {
 		return condition
 	}
 
@@ -285,6 +691,154 @@ func evalIfExpression(
 	}
 }
 
+func evalAssignExpression(
+	node *ast.AssignExpression,
+	env *object.Environment,
+) object.Object {
+	if target, ok := node.Target.(*ast.IndexExpression); ok {
+		return evalIndexAssignment(node, target, env)
+	}
+
+	name := node.Target.(*ast.Identifier).Value
+
+	current, ok := env.Get(name)
+	if !ok {
+		return newError("assignment to undeclared variable: %s", name)
+	}
+
+	val := Eval(node.Value, env)
+	if isSignal(val) {
+		return val
+	}
+
+	val = applyAssignOperator(node.Operator, current, val)
+	if isSignal(val) {
+		return val
+	}
+
+	env.Assign(name, val)
+
+	return val
+}
+
+// evalIndexAssignment updates an array element or hash entry in place.
+func evalIndexAssignment(
+	node *ast.AssignExpression,
+	target *ast.IndexExpression,
+	env *object.Environment,
+) object.Object {
+	left := Eval(target.Left, env)
+	if isSignal(left) {
+		return left
+	}
+
+	index := Eval(target.Index, env)
+	if isSignal(index) {
+		return index
+	}
+
+	val := Eval(node.Value, env)
+	if isSignal(val) {
+		return val
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be INTEGER, got %s", index.Type())
+		}
+
+		i, ok := resolveIndex(idx.Value, int64(len(left.Elements)))
+		if !ok {
+			return newError("index out of range: %d with length %d",
+				idx.Value, len(left.Elements))
+		}
+
+		val = applyAssignOperator(node.Operator, left.Elements[i], val)
+		if isSignal(val) {
+			return val
+		}
+
+		left.Elements[i] = val
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+
+		if node.Operator != "=" {
+			pair, ok := left.Pairs[key.HashKey()]
+			if !ok {
+				return newError("key not found: %s", index.Inspect())
+			}
+
+			val = applyAssignOperator(node.Operator, pair.Value, val)
+			if isSignal(val) {
+				return val
+			}
+		}
+
+		left.Set(key.HashKey(), object.HashPair{Key: index, Value: val})
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+
+	return val
+}
+
+// applyAssignOperator combines val with the current value for compound
+// assignments: x += y is x = x + y.
+func applyAssignOperator(operator string, current, val object.Object) object.Object {
+	if op := strings.TrimSuffix(operator, "="); op != "" {
+		return evalInfixExpression(op, current, val)
+	}
+	return val
+}
+
+func evalRangeExpression(
+	node *ast.RangeExpression,
+	env *object.Environment,
+) object.Object {
+	bounds := []ast.Expression{node.Start, node.Stop}
+	if node.Step != nil {
+		bounds = append(bounds, node.Step)
+	}
+
+	values := []int64{0, 0, 1}
+	for i, bound := range bounds {
+		val := Eval(bound, env)
+		if isSignal(val) {
+			return val
+		}
+
+		integer, ok := val.(*object.Integer)
+		if !ok {
+			return newError("range bounds must be INTEGER, got %s", val.Type())
+		}
+		values[i] = integer.Value
+	}
+
+	return newRange(values[0], values[1], values[2], node.Inclusive)
+}
+
+func newRange(start, stop, step int64, inclusive bool) object.Object {
+	if step == 0 {
+		return newError("range step must not be zero")
+	}
+
+	r := &object.Range{Start: start, Stop: stop, Step: step, Inclusive: inclusive}
+
+	// indexes and len are int64s, so longer ranges couldn't be used
+	if r.Len() < 0 {
+		return newError("range has more than %d elements", int64(math.MaxInt64))
+	}
+
+	return r
+}
+
 func evalIdentifier(
 	node *ast.Identifier,
 	env *object.Environment,
@@ -324,6 +878,17 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// isSignal reports whether obj is an error, or a return, break or continue
+// that came out of a block used as an expression. Like errors, these stop
+// the evaluation of whatever they turn up in and are passed up as they are.
+func isSignal(obj object.Object) bool {
+	if isError(obj) {
+		return true
+	}
+	return obj == BREAK || obj == CONTINUE ||
+		(obj != nil && obj.Type() == object.RETURN_VALUE_OBJ)
+}
+
 func evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
@@ -332,7 +897,7 @@ func evalExpressions(
 
 	for _, e := range exps {
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isSignal(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -341,15 +906,110 @@ func evalExpressions(
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// namedArg is the evaluated value of a name: value argument.
+type namedArg struct {
+	node  *ast.NamedArgument
+	value object.Object
+}
+
+// splitArguments separates the positional arguments of a call from the
+// named ones, which the parser puts last.
+func splitArguments(exps []ast.Expression) ([]ast.Expression, []*ast.NamedArgument) {
+	for i, exp := range exps {
+		if _, ok := exp.(*ast.NamedArgument); ok {
+			named := make([]*ast.NamedArgument, 0, len(exps)-i)
+			for _, exp := range exps[i:] {
+				named = append(named, exp.(*ast.NamedArgument))
+			}
+			return exps[:i], named
+		}
+	}
+
+	return exps, nil
+}
+
+// evalNamedArguments evaluates the values of named arguments. If one of
+// them gives a signal, it returns that instead.
+func evalNamedArguments(
+	named []*ast.NamedArgument,
+	env *object.Environment,
+) ([]namedArg, object.Object) {
+	var result []namedArg
+
+	for _, arg := range named {
+		evaluated := Eval(arg.Value, env)
+		if isSignal(evaluated) {
+			return nil, evaluated
+		}
+		result = append(result, namedArg{node: arg, value: evaluated})
+	}
+
+	return result, nil
+}
+
+// placeArguments puts the positional arguments and then each named one in
+// the slot of the parameter it names. The slots run up to the last one
+// given; those in between that no argument was given for are nil.
+func placeArguments(
+	params []string,
+	args []object.Object,
+	named []namedArg,
+) ([]object.Object, *object.Error) {
+	slots := make([]object.Object, max(len(params), len(args)))
+	copy(slots, args)
+	n := len(args)
+
+	for _, arg := range named {
+		name := arg.node.Name.Value
+
+		i := slices.Index(params, name)
+		switch {
+		case i < 0:
+			return nil, argumentError(arg, "unknown argument name: %s", name)
+		case i < len(args):
+			return nil, argumentError(arg, "argument %s given by position and by name", name)
+		case slots[i] != nil:
+			return nil, argumentError(arg, "duplicate argument name: %s", name)
+		}
+
+		slots[i] = arg.value
+		n = max(n, i+1)
+	}
+
+	return slots[:n], nil
+}
+
+func argumentError(arg namedArg, format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Pos = arg.node.Pos()
+	err.End = arg.node.End()
+	return err
+}
+
+func applyFunction(fn object.Object, args []object.Object, named []namedArg) object.Object {
 	switch fn := fn.(type) {
 
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args, named)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
+		if len(named) > 0 {
+			slots, err := placeArguments(fn.Params, args, named)
+			if err != nil {
+				return err
+			}
+			for i, slot := range slots {
+				if slot == nil {
+					return newError("missing argument: %s", fn.Params[i])
+				}
+			}
+			args = slots
+		}
 		return fn.Fn(args...)
 
 	default:
@@ -360,14 +1020,74 @@ func applyFunction(fn object.Object, args []object.Object) object.Object {
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
+	named []namedArg,
+) (*object.Environment, *object.Error) {
+	// parameters with defaults all come after those without
+	required := len(fn.Parameters) - len(fn.Defaults)
+
+	// named arguments can make up for missing positional ones; any still
+	// missing are reported by name below
+	switch {
+	case fn.Rest == nil && len(args) > len(fn.Parameters):
+		return nil, arityError(fn, len(args)+len(named), required)
+	case len(named) == 0 && len(args) < required:
+		return nil, arityError(fn, len(args), required)
+	}
+
+	params := make([]string, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params[i] = param.Value
+	}
+
+	slots, err := placeArguments(params, args[:min(len(args), len(params))], named)
+	if err != nil {
+		return nil, err
+	}
+
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(slots) && slots[paramIdx] != nil {
+			env.Set(param.Value, slots[paramIdx])
+			continue
+		}
+
+		def, ok := fn.Defaults[param.Value]
+		if !ok {
+			return nil, newError("missing argument: %s", param.Value)
+		}
+
+		// defaults can refer to the parameters before them
+		val := Eval(def, env)
+		if err, ok := val.(*object.Error); ok {
+			return nil, err
+		}
+		env.Set(param.Value, val)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
 	}
 
-	return env
+	return env, nil
+}
+
+func arityError(fn *object.Function, got, required int) *object.Error {
+	switch {
+	case fn.Rest != nil:
+		return newError("wrong number of arguments. got=%d, want=at least %d",
+			got, required)
+	case required == len(fn.Parameters):
+		return newError("wrong number of arguments. got=%d, want=%d",
+			got, required)
+	default:
+		return newError("wrong number of arguments. got=%d, want=%d to %d",
+			got, required, len(fn.Parameters))
+	}
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -382,6 +1102,10 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalRangeIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -391,25 +1115,142 @@ func evalIndexExpression(left, index object.Object) object.Object {
 
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
 
-	if idx < 0 || idx > max {
+	idx, ok := resolveIndex(index.(*object.Integer).Value, int64(len(arrayObject.Elements)))
+	if !ok {
 		return NULL
 	}
 
 	return arrayObject.Elements[idx]
 }
 
+// evalStringIndexExpression returns the character at index, counting in
+// characters rather than bytes.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+
+	idx, ok := resolveIndex(index.(*object.Integer).Value, int64(len(runes)))
+	if !ok {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+func evalRangeIndexExpression(rng, index object.Object) object.Object {
+	rangeObject := rng.(*object.Range)
+
+	idx, ok := resolveIndex(index.(*object.Integer).Value, rangeObject.Len())
+	if !ok {
+		return NULL
+	}
+
+	return &object.Integer{Value: rangeObject.At(idx)}
+}
+
+// resolveIndex turns an index into a sequence of the given length into an
+// offset from its start, so that -1 is the last element. It reports false if
+// the index is out of range.
+func resolveIndex(idx, length int64) (int64, bool) {
+	if idx < 0 {
+		idx += length
+	}
+
+	return idx, idx >= 0 && idx < length
+}
+
+func evalSliceExpression(
+	node *ast.SliceExpression,
+	env *object.Environment,
+) object.Object {
+	left := Eval(node.Left, env)
+	if isSignal(left) {
+		return left
+	}
+
+	var bounds [2]*object.Integer
+	for i, bound := range []ast.Expression{node.Low, node.High} {
+		if bound == nil {
+			continue
+		}
+
+		val := Eval(bound, env)
+		if isSignal(val) {
+			return val
+		}
+
+		integer, ok := val.(*object.Integer)
+		if !ok {
+			return newError("slice bounds must be INTEGER, got %s", val.Type())
+		}
+		bounds[i] = integer
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		low, high := sliceBounds(bounds[0], bounds[1], int64(len(left.Elements)))
+
+		elements := make([]object.Object, high-low)
+		copy(elements, left.Elements[low:high])
+
+		return &object.Array{Elements: elements}
+
+	case *object.String:
+		runes := []rune(left.Value)
+		low, high := sliceBounds(bounds[0], bounds[1], int64(len(runes)))
+
+		return &object.String{Value: string(runes[low:high])}
+
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+// sliceBounds resolves the bounds of a slice of a sequence of the given
+// length the way Python does: missing bounds mean the start and end,
+// negative bounds count from the end and bounds past either end are
+// clamped, so a slice is never out of range.
+func sliceBounds(low, high *object.Integer, length int64) (int64, int64) {
+	clamp := func(bound *object.Integer, missing int64) int64 {
+		if bound == nil {
+			return missing
+		}
+
+		idx := bound.Value
+		if idx < 0 {
+			idx += length
+		}
+
+		return max(0, min(idx, length))
+	}
+
+	lo, hi := clamp(low, 0), clamp(high, length)
+	if lo > hi {
+		lo = hi
+	}
+
+	return lo, hi
+}
+
 func evalHashLiteral(
 	node *ast.HashLiteral,
 	env *object.Environment,
 ) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+
+	keyNodes := node.Keys
+	if keyNodes == nil {
+		// hash literals built rather than parsed have no source order
+		for keyNode := range node.Pairs {
+			keyNodes = append(keyNodes, keyNode)
+		}
+	}
+
+	for _, keyNode := range keyNodes {
+		valueNode := node.Pairs[keyNode]
 
-	for keyNode, valueNode := range node.Pairs {
 		key := Eval(keyNode, env)
-		if isError(key) {
+		if isSignal(key) {
 			return key
 		}
 
@@ -419,15 +1260,14 @@ func evalHashLiteral(
 		}
 
 		value := Eval(valueNode, env)
-		if isError(value) {
+		if isSignal(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
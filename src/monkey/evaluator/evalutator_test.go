@@ -29,6 +29,17 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"10 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
+		{"0xF0 & 0x3C", 0x30},
+		{"0xF0 | 0x0F", 0xFF},
+		{"0xFF ^ 0x0F", 0xF0},
+		{"~0", -1},
+		{"~5 & 0xF", 10},
+		{"1 << 10", 1024},
+		{"-16 >> 2", -4},
+		{"1 << 2 + 1", 8},
 	}
 
 	for _, tt := range tests {
@@ -37,6 +48,48 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.5", 3.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 / 4.0", 2.5},
+		{"2 * 3.5 - 1", 6},
+		{"-(1.25 * 2)", -2.5},
+		{"1e-3 * 1000", 1},
+		{"7.5 % 2", 1.5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalFloatComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 < 2.5", true},
+		{"1.5 > 2", false},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 == 0.3", false},
+		{"1.5 != 1.5", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -61,12 +114,53 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 <= 1", false},
+		{"1 >= 0.5", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"5 && 0", true},
+		{"!true || !false", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalOperatorsShortCircuit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"false && 1 / 0", false},
+		{"let calls = fn() { puts(\"called\") }; true || calls()", true},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 		testBooleanObject(t, evaluated, tt.expected)
 	}
+
+	evaluated := testEval("true && undefined")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: undefined" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
 }
 
 func TestBangOperator(t *testing.T) {
@@ -223,6 +317,222 @@ if (10 > 1) {
 			`999[1]`,
 			"index operator not supported: INTEGER",
 		},
+		{
+			"10 / 0",
+			"division by zero",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"8 >> (1 - 3)",
+			"negative shift count: -2",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOLEAN",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
+		{
+			"10 % (5 - 5)",
+			"division by zero",
+		},
+		{
+			`"value: ${missing}"`,
+			"identifier not found: missing",
+		},
+		{
+			"x = 5",
+			"assignment to undeclared variable: x",
+		},
+		{
+			"let f = fn() { y += 1 }; f()",
+			"assignment to undeclared variable: y",
+		},
+		{
+			"let x = 5; x /= 0",
+			"division by zero",
+		},
+		{
+			`let x = 5; x += "a"`,
+			"type mismatch: INTEGER + STRING",
+		},
+		{
+			"let x = 5; x = missing",
+			"identifier not found: missing",
+		},
+		{
+			"let a = [1, 2]; a[2] = 3",
+			"index out of range: 2 with length 2",
+		},
+		{
+			"let a = [1, 2]; a[-3] = 3",
+			"index out of range: -3 with length 2",
+		},
+		{
+			`let a = [1, 2]; a["0"] = 3`,
+			"array index must be INTEGER, got STRING",
+		},
+		{
+			`let h = {}; h[fn(x) { x }] = 1`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`let h = {}; h["n"] += 1`,
+			`key not found: n`,
+		},
+		{
+			`let s = "abc"; s[0] = "x"`,
+			"index assignment not supported: STRING",
+		},
+		{
+			"let a = [1]; a[0] += true",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"missing[0] = 1",
+			"identifier not found: missing",
+		},
+		{
+			"while (missing) { 1 }",
+			"identifier not found: missing",
+		},
+		{
+			"for (x in 5) { x }",
+			"cannot iterate over INTEGER",
+		},
+		{
+			"0..1.5",
+			"range bounds must be INTEGER, got FLOAT",
+		},
+		{
+			`[1, 2][:"a"]`,
+			"slice bounds must be INTEGER, got STRING",
+		},
+		{
+			"5[1:2]",
+			"slice operator not supported: INTEGER",
+		},
+		{
+			`"a"..5`,
+			"range bounds must be INTEGER, got STRING",
+		},
+		{
+			"0..10 step 0",
+			"range step must not be zero",
+		},
+		{
+			"len(-5..9223372036854775807)",
+			"range has more than 9223372036854775807 elements",
+		},
+		{
+			"0..=9223372036854775807",
+			"range has more than 9223372036854775807 elements",
+		},
+		{
+			"array(0..100000000000)",
+			"range too long for `array`: 0..100000000000",
+		},
+		{
+			"array(1)",
+			"argument to `array` not supported, got INTEGER",
+		},
+		{
+			"for (x in [1, 2]) { x + true }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"let i = 0; while (true) { i += 1; if (i == 3) { i + true } }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"fn(x, y) { x }(1)",
+			"wrong number of arguments. got=1, want=2",
+		},
+		{
+			"fn(x) { x }(1, 2)",
+			"wrong number of arguments. got=2, want=1",
+		},
+		{
+			"fn(x, y = 1, z = 2) { x }(1, 2, 3, 4)",
+			"wrong number of arguments. got=4, want=1 to 3",
+		},
+		{
+			"fn(x, y, ...z) { x }(1)",
+			"wrong number of arguments. got=1, want=at least 2",
+		},
+		{
+			"fn(x) { x }(y: 1)",
+			"unknown argument name: y",
+		},
+		{
+			"fn(x, y) { x }(x: 1, x: 2)",
+			"duplicate argument name: x",
+		},
+		{
+			"fn(x, y) { x }(1, x: 2)",
+			"argument x given by position and by name",
+		},
+		{
+			"fn(x, y) { x }(y: 2)",
+			"missing argument: x",
+		},
+		{
+			"fn(x, ...xs) { x }(xs: [1])",
+			"unknown argument name: xs",
+		},
+		{
+			"fn(x) { x }(1, 2, y: 3)",
+			"wrong number of arguments. got=3, want=1",
+		},
+		{
+			"fn(x) { x }(x: 1 + true)",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"puts(x: 1)",
+			"unknown argument name: x",
+		},
+		{
+			"push(value: 1)",
+			"missing argument: array",
+		},
+		{
+			"range(end: 5)",
+			"unknown argument name: end",
+		},
+		{
+			"fn(x = 1 + true) { x }()",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"let [a, b] = 5;",
+			"cannot destructure INTEGER as an array",
+		},
+		{
+			"let [a, b] = [1, 2, 3];",
+			"array pattern expects 2 elements, got 3",
+		},
+		{
+			"let [a, b, ...c] = [1];",
+			"array pattern expects at least 2 elements, got 1",
+		},
+		{
+			`let {a} = [1];`,
+			"cannot destructure ARRAY as a hash",
+		},
+		{
+			`let {a, b} = {"a": 1};`,
+			"missing key in hash pattern: b",
+		},
+		{
+			`let [{a}] = [{"b": 1}];`,
+			"missing key in hash pattern: a",
+		},
 	}
 
 	for _, tt := range tests {
@@ -242,6 +552,265 @@ if (10 > 1) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "ERROR: 1:1: type mismatch: INTEGER + BOOLEAN"},
+		{"let a = 1;\n  a + foobar", "ERROR: 2:7: identifier not found: foobar"},
+		{
+			"let f = fn(x) {\n  -x\n};\nf(true)",
+			"ERROR: 2:3: unknown operator: -BOOLEAN",
+		},
+		{
+			"let f = fn(x) { x };\nf(1, 2)",
+			"ERROR: 2:1: wrong number of arguments. got=2, want=1",
+		},
+		{
+			"let f = fn(x = -true) { x };\nf()",
+			"ERROR: 1:16: unknown operator: -BOOLEAN",
+		},
+		{
+			"let f = fn(x) { x };\nf(1, y: 2)",
+			"ERROR: 2:6: unknown argument name: y",
+		},
+		{
+			"let [a, [b, c]] = [1, [2]];",
+			"ERROR: 1:9: array pattern expects 2 elements, got 1",
+		},
+		{
+			`let {x, y} = {"x": 1};`,
+			"ERROR: 1:9: missing key in hash pattern: y",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 10) { i += 1 }; i", 10},
+		{"let i = 0; while (false) { i += 1 }; i", 0},
+		{"while (false) { 1 }", nil},
+		{"let i = 0; while (true) { i += 1; if (i == 5) { break; } }; i", 5},
+		{"let i = 0; while (true) { i += 1; let x = if (i > 3) { break; } else { 1 }; }; i", 4},
+		{"let i = 0; while (true) { i += 1; 1 + if (i == 2) { break; } else { 0 } }; i", 2},
+		{"let i = 0; while (i < 3) { i += 1; [if (true) { continue; }] }; i", 3},
+		{`
+let i = 0;
+let sum = 0;
+while (i < 10) {
+  i += 1;
+  if (i % 2 == 0) { continue; }
+  sum += i;
+}
+sum
+`, 25},
+		{`
+let i = 0;
+let count = 0;
+while (i < 3) {
+  let j = 0;
+  while (true) {
+    j += 1;
+    if (j > 4) { break; }
+    count += 1;
+  }
+  i += 1;
+}
+count
+`, 12},
+		{`
+let find = fn(xs, x) {
+  let i = 0;
+  while (i < len(xs)) {
+    if (xs[i] == x) { return i; }
+    i += 1;
+  }
+  -1
+};
+find([4, 5, 6], 6)
+`, 2},
+		{"let i = 0; while (i < 100000) { i += 1 }; i", 100000},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x }; sum", 6},
+		{"let sum = 0; for (i, x in [10, 20, 30]) { sum += i * x }; sum", 80},
+		{"let sum = 0; for (x in []) { sum += x }; sum", 0},
+		{`let s = ""; for (k in {"b": 1, "a": 2, "c": 3}) { s += k }; s`, "bac"},
+		{`let s = ""; for (k, v in {"x": 1, "y": 2}) { s += "${k}=${v};" }; s`, "x=1;y=2;"},
+		{`let h = {"b": 1}; h["a"] = 2; h["b"] = 3; let s = ""; for (k, v in h) { s += "${k}${v}" }; s`, "b3a2"},
+		{`let s = ""; for (c in "héllo") { s = c + s }; s`, "olléh"},
+		{`let n = 0; for (i, c in "ab") { n += i }; n`, 1},
+		{"let sum = 0; for (x in range(5)) { sum += x }; sum", 10},
+		{"let sum = 0; for (x in range(2, 5)) { sum += x }; sum", 9},
+		{`let s = ""; for (x in range(10, 0, -3)) { s += "${x},"}; s`, "10,7,4,1,"},
+		{"let n = 0; for (x in range(5, 0)) { n += 1 }; n", 0},
+		{`
+let sum = 0;
+for (x in range(100)) {
+  if (x % 2 == 0) { continue; }
+  if (x > 10) { break; }
+  sum += x;
+}
+sum
+`, 25},
+		{`
+let fns = [];
+for (x in [1, 2, 3]) { fns = push(fns, fn() { x }) }
+fns[0]() + fns[1]() * 10 + fns[2]() * 100
+`, 321},
+		{`
+let contains = fn(xs, y) {
+  for (x in xs) { if (x == y) { return true; } }
+  false
+};
+contains([1, 2, 3], 2)
+`, true},
+		{`
+let xs = [1, 2];
+let n = 0;
+for (x in xs) { if (len(xs) < 5) { xs = push(xs, x) }; n += 1 }
+n
+`, 2},
+		{"let x = 5; for (x in [1]) { x }; x", 5},
+		{"let s = 0; for (x in [1, 2, 3]) { s += if (x == 2) { continue; } else { x } }; s", 4},
+		{"let xs = []; for (x in [1, 2]) { xs = push(xs, if (x == 1) { continue; } else { x }) }; len(xs)", 1},
+		{"let f = fn(y) { y }; let n = 0; for (x in [1, 2]) { n += 1; f(y: if (true) { continue; }) }; n", 2},
+		{"let f = fn() { let x = if (true) { return 5; }; 10 }; f()", 5},
+		{"for (x in [1, 2]) { x }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestRangeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"array(0..5)", []int{0, 1, 2, 3, 4}},
+		{"array(0..=5)", []int{0, 1, 2, 3, 4, 5}},
+		{"array(0..10 step 3)", []int{0, 3, 6, 9}},
+		{"array(0..=9 step 3)", []int{0, 3, 6, 9}},
+		{"array(5..0 step -2)", []int{5, 3, 1}},
+		{"array(5..=1 step -2)", []int{5, 3, 1}},
+		{"array(5..0)", []int{}},
+		{"let n = 3; array(n - 1..n * 2)", []int{2, 3, 4, 5}},
+		{"len(0..5)", 5},
+		{"len(0..=5)", 6},
+		{"len(0..1000000000000000)", 1000000000000000},
+		{"len(0..10 step 4)", 3},
+		{"(10..20)[3]", 13},
+		{"(0..100 step 5)[2]", 10},
+		{"(0..5)[5]", nil},
+		{"(0..5)[-1]", 4},
+		{"(0..5)[-6]", nil},
+		{"(0..1000000000000000)[999999999999999]", 999999999999999},
+		{"let sum = 0; for (x in 1..=100) { sum += x }; sum", 5050},
+		{"let sum = 0; for (i, x in 10..13) { sum += i }; sum", 3},
+		{"array([1, 2])", []int{1, 2}},
+		{"range(3)", "0..3"},
+		{"1..=10 step 2", "1..=10 step 2"},
+		{"len(1..=9223372036854775807)", 9223372036854775807},
+		{"(1..=9223372036854775807)[-1]", 9223372036854775807},
+		{"len(-9223372036854775807 - 1..=-2 step -1)", 0},
+		{"array(-2..9223372036854775807 step 4611686018427387904)",
+			[]int{-2, 4611686018427387902, 9223372036854775806}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("%q: obj not Array. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("%q: wrong num of elements. want=%d, got=%d",
+					tt.input, len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("%q: wrong inspect. want=%q, got=%q",
+					tt.input, expected, evaluated.Inspect())
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	chars, ok := testEval(`array("hé")`).(*object.Array)
+	if !ok || len(chars.Elements) != 2 || chars.Elements[1].Inspect() != "é" {
+		t.Errorf("array of string wrong. got=%v", chars)
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -258,6 +827,117 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + rest[0] + rest[1]", 25},
+		{"let [a, b, ...rest] = [1, 2]; len(rest)", 0},
+		{"let [...all] = [1, 2, 3]; len(all)", 3},
+		{`let {name, age: years} = {"name": "Ann", "age": 40}; "${name} ${years}"`, "Ann 40"},
+		{`let {a} = {"a": 1, "b": 2}; a`, 1},
+		{`let [x, [y, z], {w}] = [1, [2, 3], {"w": 4}]; x + y + z + w`, 10},
+		{`let {pos: [x, y]} = {"pos": [3, 4]}; x * y`, 12},
+		{"let xs = [1, 2, 3]; let [a, ...rest] = xs; rest[0] = 9; xs[1]", 2},
+		{"let f = fn() { let [a, b] = [1, 2]; a + b }; f()", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a = 10; a;", 10},
+		{"let a = 5; a = a * 2;", 10},
+		{"let a = 5; let b = 1; a = b = 3; a + b;", 6},
+		{"let a = 5; a += 2; a;", 7},
+		{"let a = 5; a -= 2; a;", 3},
+		{"let a = 5; a *= 2; a;", 10},
+		{"let a = 5; a /= 2; a;", 2},
+		{"let a = 1; let f = fn() { a = 2; }; f(); a;", 2},
+		{"let a = 1; let f = fn() { let a = 5; a = 2; }; f(); a;", 1},
+		{"let f = fn(a) { a += 1; a }; f(1);", 2},
+		{`
+let counter = fn() {
+  let count = 0;
+  fn() { count += 1 };
+};
+let next = counter();
+next();
+next();
+next();
+`, 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	testFloatObject(t, testEval("let a = 1.5; a *= 2; a"), 3.0)
+
+	str, ok := testEval(`let s = "a"; s += "b"; s`).(*object.String)
+	if !ok || str.Value != "ab" {
+		t.Errorf("string += wrong. got=%v", str)
+	}
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2, 3]; a[0] = 10; a[0];", 10},
+		{"let a = [1, 2, 3]; a[2] += 5; a[2];", 8},
+		{"let a = [1, 2, 3]; let b = a; b[1] = 7; a[1];", 7},
+		{"let a = [[1, 2], [3, 4]]; a[1][0] *= 10; a[1][0];", 30},
+		{"let a = [1, 2, 3]; a[1] = 9;", 9},
+		{"let a = [1, 2, 3]; a[-1] = 9; a[2];", 9},
+		{`let h = {"a": 1}; h["a"] = 2; h["a"];`, 2},
+		{`let h = {"a": 1}; h["b"] = 3; h["b"];`, 3},
+		{`let h = {"a": 1}; h["a"] -= 4; h["a"];`, -3},
+		{`let h = {}; h[true] = 1; h[1] = 2; h[true] + h[1];`, 3},
+		{`let h = {"xs": [1]}; h["xs"][0] = 5; h["xs"][0];`, 5},
+		{`
+let fill = fn(n) {
+  let xs = [];
+  let i = 0;
+  let step = fn() { xs = push(xs, i); i += 1; };
+  step(); step(); step();
+  xs
+};
+let xs = fill(3);
+xs[2] = xs[0] + xs[1] + xs[2];
+xs[2]
+`, 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, int64(tt.expected.(int)))
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -281,6 +961,17 @@ func TestFunctionObject(t *testing.T) {
 	if fn.Body.String() != expectedBody {
 		t.Fatalf("body is not %q. got=%q", expectedBody, fn.Body.String())
 	}
+
+	evaluated = testEval("fn(x, y = 10, ...rest) { x }")
+	fn, ok = evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expectedInspect := "fn(x, y = 10, ...rest) {\nx\n}"
+	if fn.Inspect() != expectedInspect {
+		t.Fatalf("Inspect is not %q. got=%q", expectedInspect, fn.Inspect())
+	}
 }
 
 func TestFunctionApplication(t *testing.T) {
@@ -301,6 +992,52 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3)", 9},
+		{"let n = 5; let f = fn(x = n) { x }; let n = 7; f()", 7},
+		{"let f = fn(xs = []) { push(xs, 1) }; f(); len(f())", 1},
+		{"let f = fn(first, ...others) { len(others) }; f(1)", 0},
+		{"let f = fn(first, ...others) { len(others) }; f(1, 2, 3)", 2},
+		{"let f = fn(first, ...others) { others[1] }; f(1, 2, 3)", 3},
+		{"let f = fn(...all) { len(all) }; f()", 0},
+		{"let f = fn(a, b = 2, ...c) { a + b + len(c) }; f(1)", 3},
+		{"let f = fn(a, b = 2, ...c) { a + b + len(c) }; f(1, 5, 0, 0)", 8},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(x, y) { x * 10 + y }; f(y: 2, x: 1)", 12},
+		{"let f = fn(x, y) { x * 10 + y }; f(1, y: 2)", 12},
+		{"let f = fn(x, y = 5, z = 7) { x * 100 + y * 10 + z }; f(1, z: 2)", 152},
+		{"let f = fn(x = 1, y = x + 1) { x * 10 + y }; f(x: 3)", 34},
+		{"let f = fn(x = 1, y = x + 1) { x * 10 + y }; f(y: 9)", 19},
+		{"let f = fn(a, ...rest) { a + len(rest) }; f(a: 5)", 5},
+		{"let f = fn(a, b) { a - b }; 10 |> f(b: 3)", 7},
+		{"push(array: [1], value: 2)[1]", 2},
+		{"push([1], value: 2)[1]", 2},
+		{"len(value: \"abc\")", 3},
+		{"len(value: range(0, 10, 3))", 4},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestEnclosingEnvironments(t *testing.T) {
 	input := `
 let first = 10;
@@ -330,6 +1067,25 @@ addTwo(2);`
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let double = fn(x) { x * 2 }; 5 |> double()", 10},
+		{"let add = fn(x, y) { x + y }; 5 |> add(3) |> add(2)", 10},
+		{"let sub = fn(x, y) { x - y }; 10 |> sub(3)", 7},
+		{"[1, 2] |> push(3) |> len()", 3},
+		{"let double = fn(x) { x * 2 }; 1 + 2 |> double()", 6},
+		{"let n = 0; n = 4 |> fn(x) { x + 1 }(); n", 5},
+		{"0..10 |> array() |> rest() |> len()", 9},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 
@@ -344,6 +1100,33 @@ func TestStringLiteral(t *testing.T) {
 	}
 }
 
+func TestInterpolatedString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"plain ${"string"}"`, "plain string"},
+		{`let items = [1, 2]; "you have ${len(items)} items"`, "you have 2 items"},
+		{`let x = 1.5; "${x} + ${x} = ${x + x}"`, "1.5 + 1.5 = 3.0"},
+		{`"${[1, true]} ${ {"a": 1}["a"] }"`, "[1, true] 1"},
+		{`let name = "in"; "out ${"${name}ner"}"`, "out inner"},
+		{`"\${escaped}"`, "${escaped}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. want=%q, got=%q",
+				tt.expected, str.Value)
+		}
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 
@@ -366,6 +1149,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([1, 2, 3])`, 3},
@@ -381,6 +1165,9 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`rest([])`, nil},
 		{`push([], 1)`, []int{1}},
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`range()`, "wrong number of arguments. got=0, want=1 to 3"},
+		{`range("a")`, "arguments to `range` must be INTEGER, got STRING"},
+		{`range(0, 5, 0)`, "range step must not be zero"},
 	}
 
 	for _, tt := range tests {
@@ -485,6 +1272,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -500,6 +1295,68 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4][-100:100]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"[1, 2, 3, 4][10:]", []int{}},
+		{"[][0:1]", []int{}},
+		{"let i = 1; [1, 2, 3, 4][i + 1:i + 2]", []int{3}},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", []int{1, 2, 3}},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[:-1]`, "hell"},
+		{`"héllo"[1:2]`, "é"},
+		{`"héllo"[-1]`, "o"},
+		{`"héllo"[1]`, "é"},
+		{`"hello"[10]`, nil},
+		{`"hello"[-10:2]`, "he"},
+		{`""[:]`, ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("%q: obj not Array. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("%q: wrong num of elements. want=%d, got=%d",
+					tt.input, len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("%q: obj not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("%q: wrong value. want=%q, got=%q", tt.input, expected, str.Value)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -587,8 +1444,8 @@ func TestHashIndexExpressions(t *testing.T) {
 }
 
 func testEval(input string) object.Object {
-	_, tokens := lexer.New(input)
-	p := parser.New(&tokens)
+	l := lexer.New(input)
+	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
 
@@ -610,6 +1467,21 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
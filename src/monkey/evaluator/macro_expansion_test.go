@@ -100,6 +100,22 @@ func TestExpandMacros(t *testing.T) {
 			`,
 			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
 		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+
+			2 + 2 |> reverse(10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let twice = macro(x) { quote(unquote(x) * 2); };
+
+			f(1 |> twice());
+			`,
+			`f(1 * 2)`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -118,7 +134,7 @@ func TestExpandMacros(t *testing.T) {
 }
 
 func testParseProgram(input string) *ast.Program {
-	_, tokens := lexer.New(input)
-	p := parser.New(&tokens)
+	l := lexer.New(input)
+	p := parser.New(l)
 	return p.ParseProgram()
 }
@@ -2,6 +2,8 @@ package lexer
 
 import (
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -11,183 +13,678 @@ import (
 
 const eof = -1
 
+const hexDigits = "0123456789abcdefABCDEF"
+
+// readSize is how many bytes NewReader's lexer reads from its source at once.
+const readSize = 4096
+
+// Mode controls optional lexer behavior.
+type Mode uint
+
+const (
+	ScanComments Mode = 1 << iota // emit comments as token.COMMENT instead of skipping them
+	ScanTrivia                    // attach source text, whitespace and comments to tokens; overrides ScanComments
+)
+
 type Lexer struct {
-	input        string
-	position     int // current position in input (points to current char)
-	readPosition int // current reading position in input (after current char)
-	width        int // width of last read char
-	tokens       chan token.Token
+	filename     string
+	mode         Mode
+	reader       io.Reader       // source of more input, nil once exhausted
+	readErr      error           // error other than io.EOF returned by reader
+	readBuf      []byte          // scratch space for reads from reader
+	input        string          // input read so far, starting at offset base
+	base         int             // offset of input[0] in the whole source
+	position     int             // current offset in the source (points to current char)
+	readPosition int             // current reading offset in the source (after current char)
+	width        int             // width of last read char
+	line         int             // line of readPosition
+	column       int             // column of readPosition
+	prevColumn   int             // column before the last newline read
+	start        token.Position  // position of the token being lexed
+	state        stateFn         // state to run when more tokens are needed
+	pending      []token.Token   // tokens emitted but not yet returned by NextToken
+	trivia       strings.Builder // whitespace and comments since the last token
+	triviaSplit  int             // length of trivia through its first line break, or -1
+
+	// interpolations holds, for each open ${ in a string, the number of
+	// unclosed { within it, so we know which } resumes the string
+	interpolations []int
+}
+
+func New(input string) *Lexer {
+	return NewFile("", input, 0)
 }
 
-func New(input string) (*Lexer, chan token.Token) {
-	l := &Lexer{input: input, tokens: make(chan token.Token)}
+// NewFile is like New, but records filename in the position of every token
+// and lexes according to mode.
+func NewFile(filename string, input string, mode Mode) *Lexer {
+	l := &Lexer{
+		filename:    filename,
+		mode:        mode,
+		input:       input,
+		line:        1,
+		column:      1,
+		state:       lex,
+		triviaSplit: -1,
+	}
+	l.start = l.pos()
 
-	go l.run()
+	return l
+}
 
-	return l, l.tokens
+// NewReader is like NewFile, but reads the input from r as it is needed, so
+// the source never has to be in memory all at once.
+func NewReader(filename string, r io.Reader, mode Mode) *Lexer {
+	l := NewFile(filename, "", mode)
+	l.reader = r
+	return l
 }
 
 type stateFn func(*Lexer) stateFn
 
-func (l *Lexer) run() {
-	for state := lex; state != nil; {
-		state = state(l)
+// NextToken returns the next token in the input. Lexical errors are returned
+// as ILLEGAL tokens, after which lexing carries on. Once the input is
+// exhausted it keeps returning EOF.
+func (l *Lexer) NextToken() token.Token {
+	for !l.ready() {
+		if l.state == nil {
+			return token.Token{Type: token.EOF, Pos: l.pos(), End: l.pos()}
+		}
+		l.state = l.state(l)
 	}
-	close(l.tokens)
+
+	// pending rarely holds more than one token, so shifting is cheap and
+	// reuses the backing array
+	tok := l.pending[0]
+	l.pending = append(l.pending[:0], l.pending[1:]...)
+
+	return tok
 }
 
-func lex(l *Lexer) stateFn {
-	for {
-		switch r := l.next(); {
-		case isSpace(r):
-			l.ignore()
-		case r == '=':
-			if l.peek() == '=' {
-				l.next()
-				l.emit(token.EQ)
-			} else {
-				l.emit(token.ASSIGN)
+// Mode returns the mode the lexer was created with.
+func (l *Lexer) Mode() Mode {
+	return l.mode
+}
+
+// ready reports whether the first pending token can be returned. With trivia
+// a token is held back until the next one is lexed, as that completes its
+// trailing trivia.
+func (l *Lexer) ready() bool {
+	if len(l.pending) == 0 {
+		return false
+	}
+
+	return l.mode&ScanTrivia == 0 ||
+		len(l.pending) > 1 ||
+		l.pending[0].Type == token.EOF ||
+		l.state == nil
+}
+
+// Tokens returns a channel that delivers the remaining tokens up to and
+// including EOF and is then closed. The lexer is driven by a goroutine, so
+// the channel must be drained.
+func (l *Lexer) Tokens() <-chan token.Token {
+	tokens := make(chan token.Token)
+
+	go func() {
+		defer close(tokens)
+
+		for {
+			tok := l.NextToken()
+			tokens <- tok
+
+			if tok.Type == token.EOF {
+				return
 			}
-		case r == '+':
+		}
+	}()
+
+	return tokens
+}
+
+func lex(l *Lexer) stateFn {
+	switch r := l.next(); {
+	case isSpace(r):
+		l.ignore()
+	case r == '=':
+		if l.peek() == '=' {
+			l.next()
+			l.emit(token.EQ)
+		} else {
+			l.emit(token.ASSIGN)
+		}
+	case r == '+':
+		if l.accept("=") {
+			l.emit(token.PLUS_ASSIGN)
+		} else {
 			l.emit(token.PLUS)
-		case r == '-':
+		}
+	case r == '-':
+		if l.accept("=") {
+			l.emit(token.MINUS_ASSIGN)
+		} else {
 			l.emit(token.MINUS)
-		case r == '!':
-			if l.peek() == '=' {
-				l.next()
-				l.emit(token.NOT_EQ)
-			} else {
-				l.emit(token.BANG)
-			}
-		case r == '/':
+		}
+	case r == '!':
+		if l.peek() == '=' {
+			l.next()
+			l.emit(token.NOT_EQ)
+		} else {
+			l.emit(token.BANG)
+		}
+	case r == '/':
+		switch l.peek() {
+		case '/':
+			return lexLineComment
+		case '*':
+			return lexBlockComment
+		case '=':
+			l.next()
+			l.emit(token.SLASH_ASSIGN)
+		default:
 			l.emit(token.SLASH)
-		case r == '*':
+		}
+	case r == '*':
+		if l.accept("=") {
+			l.emit(token.ASTERISK_ASSIGN)
+		} else {
 			l.emit(token.ASTERISK)
-		case r == '<':
+		}
+	case r == '%':
+		l.emit(token.PERCENT)
+	case r == '<':
+		switch {
+		case l.accept("="):
+			l.emit(token.LT_EQ)
+		case l.accept("<"):
+			l.emit(token.SHIFT_LEFT)
+		default:
 			l.emit(token.LT)
-		case r == '>':
+		}
+	case r == '>':
+		switch {
+		case l.accept("="):
+			l.emit(token.GT_EQ)
+		case l.accept(">"):
+			l.emit(token.SHIFT_RIGHT)
+		default:
 			l.emit(token.GT)
-		case r == ';':
-			l.emit(token.SEMICOLON)
-		case r == ':':
-			l.emit(token.COLON)
-		case r == ',':
-			l.emit(token.COMMA)
-		case r == '(':
-			l.emit(token.LPAREN)
-		case r == ')':
-			l.emit(token.RPAREN)
-		case r == '{':
-			l.emit(token.LSQUIRLY)
-		case r == '}':
-			l.emit(token.RSQUIRLY)
-		case r == '[':
-			l.emit(token.LBRACKET)
-		case r == ']':
-			l.emit(token.RBRACKET)
-		case r == '"':
-			return lexString
-		case '0' <= r && r <= '9':
-			l.backup()
-			return lexNumber
-		case isAlphaNumeric(r):
-			l.backup()
-			return lexIdent
-		case r == eof:
-			l.emit(token.EOF)
-			return nil
+		}
+	case r == '&':
+		if l.accept("&") {
+			l.emit(token.AND)
+		} else {
+			l.emit(token.AMPERSAND)
+		}
+	case r == '|':
+		switch {
+		case l.accept("|"):
+			l.emit(token.OR)
+		case l.accept(">"):
+			l.emit(token.PIPELINE)
+		default:
+			l.emit(token.PIPE)
+		}
+	case r == '^':
+		l.emit(token.CARET)
+	case r == '~':
+		l.emit(token.TILDE)
+	case r == '.' && l.peek() == '.':
+		l.next()
+		switch {
+		case l.accept("="):
+			l.emit(token.DOTDOT_EQ)
+		case l.accept("."):
+			l.emit(token.ELLIPSIS)
 		default:
-			return l.errorf("unrecognized character in action: %#U", r)
+			l.emit(token.DOTDOT)
+		}
+	case r == ';':
+		l.emit(token.SEMICOLON)
+	case r == ':':
+		l.emit(token.COLON)
+	case r == ',':
+		l.emit(token.COMMA)
+	case r == '(':
+		l.emit(token.LPAREN)
+	case r == ')':
+		l.emit(token.RPAREN)
+	case r == '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1]++
 		}
+		l.emit(token.LSQUIRLY)
+	case r == '}':
+		if n := len(l.interpolations); n > 0 {
+			if l.interpolations[n-1] == 0 {
+				l.interpolations = l.interpolations[:n-1]
+				return lexStringContinue
+			}
+			l.interpolations[n-1]--
+		}
+		l.emit(token.RSQUIRLY)
+	case r == '[':
+		l.emit(token.LBRACKET)
+	case r == ']':
+		l.emit(token.RBRACKET)
+	case r == '"':
+		return lexString
+	case r == '`':
+		return lexRawString
+	case isDigit(r):
+		l.backup()
+		return lexNumber
+	case isAlphaNumeric(r):
+		l.backup()
+		return lexIdent
+	case r == eof:
+		if err := l.readErr; err != nil {
+			l.readErr = nil
+			return l.errorf("read error: %s", err)
+		}
+		if len(l.interpolations) > 0 {
+			l.interpolations = nil
+			return l.errorf("unterminated string interpolation")
+		}
+		l.emit(token.EOF)
+		return nil
+	default:
+		return l.errorf("unrecognized character in action: %#U", r)
 	}
+
+	return lex
 }
 
+// lexString scans a quoted string, whose literal is its content with escape
+// sequences interpreted. The opening " has already been consumed.
 func lexString(l *Lexer) stateFn {
-	if l.next() == '"' {
-		// handle the case where the string is empty
-		l.readPosition = l.position
-		l.emit(token.STRING)
+	return lexStringPart(l, token.STRING, token.STRING_START)
+}
 
-		// move the position to the next character
-		l.position += 1
-		l.readPosition += 2
+// lexStringContinue scans the rest of an interpolated string after the }
+// that closes an interpolation.
+func lexStringContinue(l *Lexer) stateFn {
+	return lexStringPart(l, token.STRING_END, token.STRING_MIDDLE)
+}
 
-		return lex
-	}
+// lexStringPart scans string content up to the closing ", emitted as end,
+// or up to the next ${, emitted as interpolation.
+func lexStringPart(l *Lexer, end, interpolation token.TokenType) stateFn {
+	var value strings.Builder
 
 	for {
+		pos := l.pos()
+
 		switch r := l.next(); {
 		case r == '"':
-			// handle the " character at the beginning and end of the string
-			startStringPosition := l.position + 1
-			endStringPosition := l.readPosition - 1
+			l.emitLiteral(end, value.String())
+			return lex
+		case r == '$' && l.peek() == '{':
+			l.next()
+			l.emitLiteral(interpolation, value.String())
+			l.interpolations = append(l.interpolations, 0)
+			return lex
+		case r == '\\' && l.peek() != eof:
+			// report a bad escape but keep the rest of the string
+			escaped, err := l.lexEscape()
+			if err != nil {
+				l.emitError(pos, "%s", err)
+				continue
+			}
+			value.WriteRune(escaped)
+		case r == '\\' || r == eof:
+			return l.errorf("unterminated string")
+		default:
+			value.WriteRune(r)
+		}
+	}
+}
+
+// lexEscape scans an escape sequence after its backslash and returns the
+// rune it stands for.
+func (l *Lexer) lexEscape() (rune, error) {
+	switch r := l.next(); r {
+	case 'n':
+		return '\n', nil
+	case 't':
+		return '\t', nil
+	case 'r':
+		return '\r', nil
+	case '\\', '"', '$':
+		return r, nil
+	case 'u':
+		return l.lexUnicodeEscape()
+	default:
+		return 0, fmt.Errorf("invalid escape sequence: \\%c", r)
+	}
+}
 
-			l.position = startStringPosition
-			l.readPosition = endStringPosition
+// lexUnicodeEscape scans the {XXXX} part of a \u{XXXX} escape sequence,
+// which holds one to six hex digits.
+func (l *Lexer) lexUnicodeEscape() (rune, error) {
+	if !l.accept("{") {
+		return 0, fmt.Errorf("invalid unicode escape: expected {")
+	}
 
-			l.emit(token.STRING)
+	start := l.readPosition
+	l.acceptRun(hexDigits)
+	digits := l.text(start, l.readPosition)
 
-			l.position = endStringPosition + 1
-			l.readPosition = endStringPosition + 1
+	if !l.accept("}") || len(digits) == 0 || len(digits) > 6 {
+		return 0, fmt.Errorf("invalid unicode escape: \\u{%s", digits)
+	}
 
+	value, _ := strconv.ParseUint(digits, 16, 32)
+	if !utf8.ValidRune(rune(value)) {
+		return 0, fmt.Errorf("invalid unicode code point: \\u{%s}", digits)
+	}
+
+	return rune(value), nil
+}
+
+// lexRawString scans a `-quoted string, which may span lines and has no
+// escape sequences. The opening ` has already been consumed.
+func lexRawString(l *Lexer) stateFn {
+	for {
+		switch l.next() {
+		case '`':
+			// the literal excludes the ` characters at the beginning and end
+			l.emitLiteral(token.STRING, l.text(l.position+1, l.readPosition-1))
 			return lex
+		case eof:
+			return l.errorf("unterminated raw string")
+		}
+	}
+}
+
+// lexPrefixedInteger scans the digits of a 0x, 0b or 0o integer literal after
+// its prefix.
+func lexPrefixedInteger(l *Lexer, digits string) stateFn {
+	start := l.readPosition
+	l.acceptRun(digits)
+
+	if l.readPosition == start || isAlphaNumeric(l.peek()) {
+		return l.badNumber()
+	}
+
+	l.emit(token.INT)
+
+	return lex
+}
+
+// lexLineComment scans a comment up to, but not including, the end of the
+// line. The leading / has already been consumed.
+func lexLineComment(l *Lexer) stateFn {
+	for {
+		if r := l.next(); r == '\n' || r == eof {
+			l.backup()
+			break
+		}
+	}
+
+	l.emitComment()
+
+	return lex
+}
+
+// lexBlockComment scans a possibly nested /* */ comment. The leading / has
+// already been consumed.
+func lexBlockComment(l *Lexer) stateFn {
+	l.next()
+
+	for depth := 1; depth > 0; {
+		switch r := l.next(); {
+		case r == '/' && l.peek() == '*':
+			l.next()
+			depth++
+		case r == '*' && l.peek() == '/':
+			l.next()
+			depth--
 		case r == eof:
-			return l.errorf("unterminated string")
+			return l.errorf("unterminated comment")
 		}
 	}
+
+	l.emitComment()
+
+	return lex
 }
 
 func lexNumber(l *Lexer) stateFn {
-	digits := "0123456789"
+	// digit separators are checked by the parser, e.g. 1_000 but not 1__000
+	digits := "0123456789_"
+
+	if l.accept("0") {
+		switch {
+		case l.accept("xX"):
+			return lexPrefixedInteger(l, hexDigits+"_")
+		case l.accept("bB"):
+			return lexPrefixedInteger(l, "01_")
+		case l.accept("oO"):
+			return lexPrefixedInteger(l, "01234567_")
+		}
+	}
+
 	l.acceptRun(digits)
 
-	if isAlphaNumeric(l.peek()) {
+	typ := token.TokenType(token.INT)
+
+	// a fraction needs a digit after the dot, so 1.foo stays an integer
+	if l.peek() == '.' && isDigit(l.peekSecond()) {
 		l.next()
-		return l.errorf("bad number syntax: %q", l.input[l.position:l.readPosition])
+		l.acceptRun(digits)
+		typ = token.FLOAT
 	}
 
-	l.emit(token.INT)
+	if l.accept("eE") {
+		l.accept("+-")
+		if !isDigit(l.peek()) {
+			return l.badNumber()
+		}
+		l.acceptRun(digits)
+		typ = token.FLOAT
+	}
+
+	if isAlphaNumeric(l.peek()) {
+		return l.badNumber()
+	}
+
+	l.emit(typ)
 
 	return lex
 }
 
+// badNumber reports a malformed number, skipping the letters and digits that
+// follow it so they are not lexed as an identifier.
+func (l *Lexer) badNumber() stateFn {
+	for isAlphaNumeric(l.next()) {
+	}
+	l.backup()
+
+	return l.errorf("bad number syntax: %q", l.text(l.position, l.readPosition))
+}
+
 func lexIdent(l *Lexer) stateFn {
 	for isAlphaNumeric(l.next()) {
 	}
 	l.backup()
 
-	l.emit(token.LookupIdent(l.input[l.position:l.readPosition]))
+	l.emit(token.LookupIdent(l.text(l.position, l.readPosition)))
 
 	return lex
 }
 
 func (l *Lexer) next() (r rune) {
-	if l.readPosition >= len(l.input) {
+	// make sure a whole rune is buffered, even if it was split across reads
+	for l.reader != nil && l.base+len(l.input)-l.readPosition < utf8.UTFMax {
+		l.fill()
+	}
+
+	if l.readPosition >= l.base+len(l.input) {
 		l.width = 0
 		return eof
 	}
-	r, l.width = utf8.DecodeRuneInString(l.input[l.readPosition:])
+	r, l.width = utf8.DecodeRuneInString(l.input[l.readPosition-l.base:])
 	l.readPosition += l.width
+	if r == '\n' {
+		l.line++
+		l.prevColumn = l.column
+		l.column = 1
+	} else {
+		l.column++
+	}
 	return r
 }
 
+// fill reads more input from the reader, dropping the input before the
+// current token, which is no longer needed.
+func (l *Lexer) fill() {
+	keep := l.input[l.position-l.base:]
+
+	// read at least as much again as is kept, so that a token far longer
+	// than readSize is copied a few times rather than once per read
+	size := max(readSize, 2*len(keep))
+	if len(l.readBuf) < size {
+		l.readBuf = make([]byte, size)
+	}
+
+	var n int
+	var err error
+	for err == nil && (n == 0 || n < len(keep)) {
+		var m int
+		m, err = l.reader.Read(l.readBuf[n:size])
+		n += m
+	}
+
+	var input strings.Builder
+	input.Grow(len(keep) + n)
+	input.WriteString(keep)
+	input.Write(l.readBuf[:n])
+
+	l.input = input.String()
+	l.base = l.position
+
+	if err != nil {
+		if err != io.EOF {
+			l.readErr = err
+		}
+		l.reader = nil
+	}
+}
+
+// text returns the input between the offsets from and to, which must not be
+// before the start of the current token.
+func (l *Lexer) text(from, to int) string {
+	return l.input[from-l.base : to-l.base]
+}
+
 func (l *Lexer) peek() rune {
 	r := l.next()
 	l.backup()
 	return r
 }
 
+// peekSecond returns the rune after the next one without consuming either.
+func (l *Lexer) peekSecond() rune {
+	readPosition, width, line, column, prevColumn := l.readPosition, l.width, l.line, l.column, l.prevColumn
+	l.next()
+	r := l.next()
+	l.readPosition, l.width, l.line, l.column, l.prevColumn = readPosition, width, line, column, prevColumn
+	return r
+}
+
 func (l *Lexer) backup() {
+	if l.width == 0 {
+		return
+	}
 	l.readPosition -= l.width
+	if l.input[l.readPosition-l.base] == '\n' {
+		l.line--
+		l.column = l.prevColumn
+	} else {
+		l.column--
+	}
+}
+
+// pos returns the position of readPosition.
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.readPosition,
+		Line:     l.line,
+		Column:   l.column,
+	}
 }
 
 func (l *Lexer) emit(t token.TokenType) {
-	l.tokens <- token.Token{Type: t, Literal: l.input[l.position:l.readPosition]}
+	l.emitLiteral(t, l.text(l.position, l.readPosition))
+}
+
+func (l *Lexer) emitLiteral(t token.TokenType, literal string) {
+	end := l.pos()
+
+	tok := token.Token{Type: t, Literal: literal, Pos: l.start, End: end}
+	if l.mode&ScanTrivia != 0 {
+		tok.Raw = l.text(l.position, l.readPosition)
+	}
+	l.emitToken(tok)
+
 	l.position = l.readPosition
+	l.start = end
+}
+
+// emitToken queues tok, first handing out the trivia seen since the last
+// token: up to the first line break it trails the last token, the rest leads
+// tok.
+func (l *Lexer) emitToken(tok token.Token) {
+	if l.mode&ScanTrivia != 0 {
+		trivia := l.trivia.String()
+
+		if n := len(l.pending); n > 0 {
+			split := l.triviaSplit
+			if split < 0 {
+				split = len(trivia)
+			}
+			l.pending[n-1].Trailing = trivia[:split]
+			trivia = trivia[split:]
+		}
+		tok.Leading = trivia
+
+		l.trivia.Reset()
+		l.triviaSplit = -1
+	}
+
+	l.pending = append(l.pending, tok)
+}
+
+func (l *Lexer) emitComment() {
+	if l.mode&ScanComments != 0 && l.mode&ScanTrivia == 0 {
+		l.emit(token.COMMENT)
+	} else {
+		l.ignore()
+	}
 }
 
 func (l *Lexer) ignore() {
+	if l.mode&ScanTrivia != 0 {
+		text := l.text(l.position, l.readPosition)
+		l.trivia.WriteString(text)
+		// the previous token keeps the trivia up to and including its newline
+		if text == "\n" && l.triviaSplit < 0 {
+			l.triviaSplit = l.trivia.Len()
+		}
+	}
+
 	l.position = l.readPosition
+	l.start = l.pos()
+}
+
+func (l *Lexer) accept(valid string) bool {
+	if strings.ContainsRune(valid, l.next()) {
+		return true
+	}
+	l.backup()
+	return false
 }
 
 func (l *Lexer) acceptRun(valid string) {
@@ -196,18 +693,32 @@ func (l *Lexer) acceptRun(valid string) {
 	l.backup()
 }
 
+// errorf reports the text consumed since the start of the current token as
+// an ILLEGAL token and resumes lexing after it.
 func (l *Lexer) errorf(format string, args ...interface{}) stateFn {
-	l.tokens <- token.Token{
+	l.emitLiteral(token.ILLEGAL, fmt.Sprintf(format, args...))
+	return lex
+}
+
+// emitError emits an ILLEGAL token from pos to the current position without
+// discarding the current token, whose source text it leaves alone.
+func (l *Lexer) emitError(pos token.Position, format string, args ...interface{}) {
+	l.emitToken(token.Token{
 		Type:    token.ILLEGAL,
 		Literal: fmt.Sprintf(format, args...),
-	}
-	return nil
+		Pos:     pos,
+		End:     l.pos(),
+	})
 }
 
 func isSpace(r rune) bool {
 	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
 }
 
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
 func isAlphaNumeric(r rune) bool {
 	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
 }
@@ -1,8 +1,11 @@
 package lexer
 
 import (
-	"fmt"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"monkey/token"
 )
@@ -16,7 +19,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -142,22 +145,686 @@ macro(x, y) { x + y; };
 		{token.EOF, ""},
 	}
 
-	_, tokens := New(input)
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  \"héllo\" + y\n"
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedOffset int
+		expectedLine   int
+		expectedColumn int
+		expectedEnd    int
+	}{
+		{token.LET, 0, 1, 1, 3},
+		{token.IDENT, 4, 1, 5, 5},
+		{token.ASSIGN, 6, 1, 7, 7},
+		{token.INT, 8, 1, 9, 9},
+		{token.SEMICOLON, 9, 1, 10, 10},
+		{token.STRING, 13, 2, 3, 21},
+		{token.PLUS, 22, 2, 11, 23},
+		{token.IDENT, 24, 2, 13, 25},
+		{token.EOF, 26, 3, 1, 26},
+	}
+
+	tokens := NewFile("test.mk", input, 0).Tokens()
 
 	index := 0
-	for token := range tokens {
-		if token.Type != tests[index].expectedType {
-			fmt.Println(token, tests[index])
+	for tok := range tokens {
+		tt := tests[index]
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				index, tt.expectedType, tok.Type)
+		}
+
+		expected := token.Position{
+			Filename: "test.mk",
+			Offset:   tt.expectedOffset,
+			Line:     tt.expectedLine,
+			Column:   tt.expectedColumn,
+		}
+		if tok.Pos != expected {
+			t.Errorf("tests[%d] - pos wrong. expected=%+v, got=%+v",
+				index, expected, tok.Pos)
+		}
+
+		if tok.End.Offset != tt.expectedEnd {
+			t.Errorf("tests[%d] - end offset wrong. expected=%d, got=%d",
+				index, tt.expectedEnd, tok.End.Offset)
+		}
+
+		index++
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `a <= b >= c < d > e % f && g || h & i | j ^ ~k << l >> m
+n += 1 -= 2 *= 3 /= 4 |> o`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.LT, "<"},
+		{token.IDENT, "d"},
+		{token.GT, ">"},
+		{token.IDENT, "e"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "f"},
+		{token.AND, "&&"},
+		{token.IDENT, "g"},
+		{token.OR, "||"},
+		{token.IDENT, "h"},
+		{token.AMPERSAND, "&"},
+		{token.IDENT, "i"},
+		{token.PIPE, "|"},
+		{token.IDENT, "j"},
+		{token.CARET, "^"},
+		{token.TILDE, "~"},
+		{token.IDENT, "k"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.IDENT, "l"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.IDENT, "m"},
+		{token.IDENT, "n"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "2"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "3"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "4"},
+		{token.PIPELINE, "|>"},
+		{token.IDENT, "o"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing comment
+/* block /* nested */ comment */ x / 2;
+`
+
+	tests := []struct {
+		mode            Mode
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{ScanComments, token.COMMENT, "// leading comment"},
+		{0, token.LET, "let"},
+		{0, token.IDENT, "x"},
+		{0, token.ASSIGN, "="},
+		{0, token.INT, "5"},
+		{0, token.SEMICOLON, ";"},
+		{ScanComments, token.COMMENT, "// trailing comment"},
+		{ScanComments, token.COMMENT, "/* block /* nested */ comment */"},
+		{0, token.IDENT, "x"},
+		{0, token.SLASH, "/"},
+		{0, token.INT, "2"},
+		{0, token.SEMICOLON, ";"},
+		{0, token.EOF, ""},
+	}
+
+	for _, mode := range []Mode{0, ScanComments} {
+		expected := []token.Token{}
+		for _, tt := range tests {
+			if tt.mode&mode == tt.mode {
+				expected = append(expected,
+					token.Token{Type: tt.expectedType, Literal: tt.expectedLiteral})
+			}
+		}
+
+		tokens := NewFile("", input, mode).Tokens()
+
+		index := 0
+		for tok := range tokens {
+			if index >= len(expected) {
+				t.Fatalf("mode %d - too many tokens, got %q", mode, tok.Literal)
+			}
+
+			if tok.Type != expected[index].Type {
+				t.Fatalf("mode %d, tests[%d] - tokentype wrong. expected=%q, got=%q",
+					mode, index, expected[index].Type, tok.Type)
+			}
+
+			if tok.Literal != expected[index].Literal {
+				t.Fatalf("mode %d, tests[%d] - literal wrong. expected=%q, got=%q",
+					mode, index, expected[index].Literal, tok.Literal)
+			}
+
+			index++
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	tokens := New("5 /* outer /* inner */").Tokens()
+
+	var illegal token.Token
+	for tok := range tokens {
+		if tok.Type == token.ILLEGAL {
+			illegal = tok
+		}
+	}
 
+	if illegal.Type != token.ILLEGAL {
+		t.Fatalf("no ILLEGAL token found")
+	}
+
+	if illegal.Literal != "unterminated comment" {
+		t.Errorf("literal wrong. expected=%q, got=%q",
+			"unterminated comment", illegal.Literal)
+	}
+
+	if illegal.Pos.Column != 3 {
+		t.Errorf("column wrong. expected=3, got=%d", illegal.Pos.Column)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"42", token.INT, "42"},
+		{"3.14", token.FLOAT, "3.14"},
+		{"1e-9", token.FLOAT, "1e-9"},
+		{"2.5E+3", token.FLOAT, "2.5E+3"},
+		{"10e3", token.FLOAT, "10e3"},
+		{"1e", token.ILLEGAL, `bad number syntax: "1e"`},
+		{"3.14abc", token.ILLEGAL, `bad number syntax: "3.14abc"`},
+		{"0xFF", token.INT, "0xFF"},
+		{"0XdeadBEEF", token.INT, "0XdeadBEEF"},
+		{"0b1010", token.INT, "0b1010"},
+		{"0o755", token.INT, "0o755"},
+		{"1_000_000", token.INT, "1_000_000"},
+		{"0x_ff_ff", token.INT, "0x_ff_ff"},
+		{"1_000.5e1_0", token.FLOAT, "1_000.5e1_0"},
+		{"0x", token.ILLEGAL, `bad number syntax: "0x"`},
+		{"0b102", token.ILLEGAL, `bad number syntax: "0b102"`},
+		{"0o8", token.ILLEGAL, `bad number syntax: "0o8"`},
+		{"0xFG", token.ILLEGAL, `bad number syntax: "0xFG"`},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("%q - tokentype wrong. expected=%q, got=%q",
+				tt.input, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%q - literal wrong. expected=%q, got=%q",
+				tt.input, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIntegerFollowedByDot(t *testing.T) {
+	tok := New("1.x").NextToken()
+	if tok.Type != token.INT || tok.Literal != "1" {
+		t.Fatalf("first token wrong. got=%q %q", tok.Type, tok.Literal)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	input := `1..5 0..=n 1.5..2 ...rest`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "1"},
+		{token.DOTDOT, ".."},
+		{token.INT, "5"},
+		{token.INT, "0"},
+		{token.DOTDOT_EQ, "..="},
+		{token.IDENT, "n"},
+		{token.FLOAT, "1.5"},
+		{token.DOTDOT, ".."},
+		{token.INT, "2"},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
 			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
-				index, tests[index].expectedType, token.Type)
+				i, tt.expectedType, tok.Type)
 		}
 
-		if token.Literal != tests[index].expectedLiteral {
+		if tok.Literal != tt.expectedLiteral {
 			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
-				index, tests[index].expectedLiteral, token.Literal)
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"she said \"hi\""`, token.STRING, `she said "hi"`},
+		{`"a\tb\nc\\d\r"`, token.STRING, "a\tb\nc\\d\r"},
+		{`"\u{48}\u{e9}\u{1F600}"`, token.STRING, "Hé😀"},
+		{"`raw \\n \"string\"\nspanning lines`", token.STRING, "raw \\n \"string\"\nspanning lines"},
+		{"``", token.STRING, ""},
+		{`"bad \q escape"`, token.ILLEGAL, `invalid escape sequence: \q`},
+		{`"\u{}"`, token.ILLEGAL, `invalid unicode escape: \u{`},
+		{`"\u{1234567}"`, token.ILLEGAL, `invalid unicode escape: \u{1234567`},
+		{`"\u41"`, token.ILLEGAL, `invalid unicode escape: expected {`},
+		{`"\u{D800}"`, token.ILLEGAL, `invalid unicode code point: \u{D800}`},
+		{`"unterminated \"`, token.ILLEGAL, "unterminated string"},
+		{"`unterminated", token.ILLEGAL, "unterminated raw string"},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("%q - tokentype wrong. expected=%q, got=%q",
+				tt.input, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%q - literal wrong. expected=%q, got=%q",
+				tt.input, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestEscapeErrorPosition(t *testing.T) {
+	tok := New(`"ab\qc"`).NextToken()
+
+	if tok.Type != token.ILLEGAL {
+		t.Fatalf("tokentype wrong. expected=%q, got=%q", token.ILLEGAL, tok.Type)
+	}
+
+	if tok.Pos.Column != 4 {
+		t.Errorf("column wrong. expected=4, got=%d", tok.Pos.Column)
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	input := `"Hello ${name}, ${ {"n": 1}["n"] } and ${"in${x}ner"}!" "\${not}"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING_START, "Hello "},
+		{token.IDENT, "name"},
+		{token.STRING_MIDDLE, ", "},
+		{token.LSQUIRLY, "{"},
+		{token.STRING, "n"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RSQUIRLY, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "n"},
+		{token.RBRACKET, "]"},
+		{token.STRING_MIDDLE, " and "},
+		{token.STRING_START, "in"},
+		{token.IDENT, "x"},
+		{token.STRING_END, "ner"},
+		{token.STRING_END, "!"},
+		{token.STRING, "${not}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnterminatedInterpolation(t *testing.T) {
+	tokens := New(`"a ${x`).Tokens()
+
+	var illegal token.Token
+	for tok := range tokens {
+		if tok.Type == token.ILLEGAL {
+			illegal = tok
+		}
+	}
+
+	if illegal.Type != token.ILLEGAL {
+		t.Fatalf("no ILLEGAL token found")
+	}
+
+	if illegal.Literal != "unterminated string interpolation" {
+		t.Errorf("literal wrong. got=%q", illegal.Literal)
+	}
+}
+
+func TestNextTokenAfterEOF(t *testing.T) {
+	l := New("x")
+
+	if tok := l.NextToken(); tok.Type != token.IDENT {
+		t.Fatalf("tokentype wrong. expected=%q, got=%q", token.IDENT, tok.Type)
+	}
+
+	for i := 0; i < 3; i++ {
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", token.EOF, tok.Type)
+		}
+	}
+}
+
+func TestTokensChannel(t *testing.T) {
+	l := New("let x = 5;")
+
+	expected := []token.TokenType{
+		token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON, token.EOF,
+	}
+
+	index := 0
+	for tok := range l.Tokens() {
+		if index >= len(expected) {
+			t.Fatalf("too many tokens, got %q", tok.Type)
+		}
+
+		if tok.Type != expected[index] {
+			t.Fatalf("tokens[%d] - tokentype wrong. expected=%q, got=%q",
+				index, expected[index], tok.Type)
 		}
 
 		index++
 	}
+
+	if index != len(expected) {
+		t.Fatalf("channel closed after %d tokens, want %d", index, len(expected))
+	}
+}
+
+const benchmarkSource = `let fibonacci = fn(x) {
+  if (x < 2) {
+    return x; // the base case
+  }
+  fibonacci(x - 1) + fibonacci(x - 2);
+};
+let map = fn(arr, f) { if (len(arr) == 0) { [] } else { push(map(rest(arr), f), f(first(arr))) } };
+let people = [{"name": "Alice", "age": 24}, {"name": "Anna", "age": 28}];
+puts("${people[0]["name"]} is ${people[0]["age"]}", 3.14, 0xFF);
+`
+
+func benchmarkInput() string {
+	return strings.Repeat(benchmarkSource, 1000)
+}
+
+func BenchmarkNextToken(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := New(input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+func BenchmarkTokensChannel(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for range New(input).Tokens() {
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	inputs := []string{
+		benchmarkSource,
+		"let s = \"héllo wörld 😀\";\n// ünïcödé comment\nlet t = `" +
+			strings.Repeat("long raw string ", 1000) + "`;\n",
+		strings.Repeat("let x = 1;\n", 1000),
+	}
+
+	readers := map[string]func(string) io.Reader{
+		"one byte": func(s string) io.Reader { return iotest.OneByteReader(strings.NewReader(s)) },
+		"half":     func(s string) io.Reader { return iotest.HalfReader(strings.NewReader(s)) },
+		"whole":    func(s string) io.Reader { return strings.NewReader(s) },
+		"data+EOF": func(s string) io.Reader { return iotest.DataErrReader(strings.NewReader(s)) },
+	}
+
+	for _, input := range inputs {
+		for name, newReader := range readers {
+			expected := NewFile("test.mk", input, ScanComments)
+			l := NewReader("test.mk", newReader(input), ScanComments)
+
+			for i := 0; ; i++ {
+				want := expected.NextToken()
+				got := l.NextToken()
+
+				if got != want {
+					t.Fatalf("%s reader, token %d wrong. expected=%+v, got=%+v",
+						name, i, want, got)
+				}
+
+				if want.Type == token.EOF {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	input := `// header
+let x = 5; // five
+  /* lead */ x
+`
+
+	tests := []struct {
+		expectedType     token.TokenType
+		expectedRaw      string
+		expectedLeading  string
+		expectedTrailing string
+	}{
+		{token.LET, "let", "// header\n", " "},
+		{token.IDENT, "x", "", " "},
+		{token.ASSIGN, "=", "", " "},
+		{token.INT, "5", "", ""},
+		{token.SEMICOLON, ";", "", " // five\n"},
+		{token.IDENT, "x", "  /* lead */ ", "\n"},
+		{token.EOF, "", "", ""},
+	}
+
+	l := NewFile("", input, ScanComments|ScanTrivia)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Raw != tt.expectedRaw {
+			t.Fatalf("tests[%d] - raw wrong. expected=%q, got=%q",
+				i, tt.expectedRaw, tok.Raw)
+		}
+
+		if tok.Leading != tt.expectedLeading {
+			t.Fatalf("tests[%d] - leading wrong. expected=%q, got=%q",
+				i, tt.expectedLeading, tok.Leading)
+		}
+
+		if tok.Trailing != tt.expectedTrailing {
+			t.Fatalf("tests[%d] - trailing wrong. expected=%q, got=%q",
+				i, tt.expectedTrailing, tok.Trailing)
+		}
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(errors.New("disk on fire")))
+	l := NewReader("", r, 0)
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ILLEGAL, "read error: disk on fire"},
+	}
+
+	for i, tt := range expected {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func BenchmarkNewReader(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := NewReader("", strings.NewReader(input), 0)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+// BenchmarkNewReaderLargeToken lexes a single comment many times longer
+// than one read, which has to be kept whole across reads.
+func BenchmarkNewReaderLargeToken(b *testing.B) {
+	input := "/*" + strings.Repeat("x", 4<<20) + "*/ let x = 1;"
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := NewReader("", strings.NewReader(input), 0)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := "let a = 5 @ 3;\nlet b = 3.14abc + 1;\nlet c = \"bad \\q and \\z\";\nlet d = 0x;\n# \"open"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.ILLEGAL, "unrecognized character in action: U+0040 '@'"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "b"},
+		{token.ASSIGN, "="},
+		{token.ILLEGAL, `bad number syntax: "3.14abc"`},
+		{token.PLUS, "+"},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "c"},
+		{token.ASSIGN, "="},
+		{token.ILLEGAL, `invalid escape sequence: \q`},
+		{token.ILLEGAL, `invalid escape sequence: \z`},
+		{token.STRING, "bad  and "},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "d"},
+		{token.ASSIGN, "="},
+		{token.ILLEGAL, `bad number syntax: "0x"`},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "unrecognized character in action: U+0023 '#'"},
+		{token.ILLEGAL, "unterminated string"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q (%q)",
+				i, tt.expectedType, tok.Type, tok.Literal)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
 }
@@ -2,12 +2,22 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"monkey/diagnostic"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
 	"monkey/repl"
 	"os"
 	"os/user"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runFile(os.Args[1]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -17,3 +27,53 @@ func main() {
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// runFile runs the program in the named file, or in stdin if name is "-",
+// and returns the exit code.
+func runFile(name string) int {
+	var in io.Reader
+	var lines diagnostic.LineFunc
+	if name == "-" {
+		name = "<stdin>"
+		in = os.Stdin
+
+		// stdin can't be read again, and keeping all of it in case an error
+		// has to quote it would defeat streaming, so errors go unquoted
+		lines = func(int) (string, bool) { return "", false }
+	} else {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+		lines = diagnostic.FileLines(name)
+	}
+
+	color := diagnostic.ColorEnabled(os.Stderr)
+
+	l := lexer.NewReader(name, in, 0)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.ParseErrors()) != 0 {
+		for _, err := range p.ParseErrors() {
+			diagnostic.FprintLines(os.Stderr, diagnostic.FromParseError(err), lines, color)
+		}
+		return 1
+	}
+
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+
+	evaluator.DefineMacros(program, macroEnv)
+	expanded := evaluator.ExpandMacros(program, macroEnv)
+
+	if err, ok := evaluator.Eval(expanded, env).(*object.Error); ok {
+		diagnostic.FprintLines(os.Stderr, diagnostic.FromError(err), lines, color)
+		return 1
+	}
+
+	return 0
+}
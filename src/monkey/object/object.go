@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 
 	"monkey/ast"
+	"monkey/token"
 )
 
 type BuiltinFunction func(args ...Object) Object
@@ -18,16 +21,20 @@ const (
 	ERROR_OBJ = "ERROR"
 
 	INTEGER_OBJ = "INTEGER"
+	FLOAT_OBJ   = "FLOAT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	STRING_OBJ  = "STRING"
 
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 
 	FUNCTION_OBJ = "FUNCTION"
 	BUILTIN_OBJ  = "BUILTIN"
 
 	ARRAY_OBJ = "ARRAY"
 	HASH_OBJ  = "HASH"
+	RANGE_OBJ = "RANGE"
 
 	QUOTE_OBJ = "QUOTE"
 	MACRO_OBJ = "MACRO"
@@ -57,6 +64,25 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+
+	// keep whole floats distinguishable from integers, e.g. 2.0 not 2
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+
+	return s
+}
+func (f *Float) HashKey() HashKey {
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -87,15 +113,36 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Break and Continue signal a break or continue statement to the enclosing
+// loop, the way ReturnValue signals a return to the enclosing function.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 type Error struct {
 	Message string
+	Pos     token.Position // where the error occurred, if known
+	End     token.Position // where the node that caused it ends, if known
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   map[string]ast.Expression // evaluated on each call that leaves them out
+	Rest       *ast.Identifier           // collects any extra arguments
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -106,7 +153,14 @@ func (f *Function) Inspect() string {
 
 	params := []string{}
 	for _, p := range f.Parameters {
-		params = append(params, p.String())
+		if def, ok := f.Defaults[p.Value]; ok {
+			params = append(params, p.String()+" = "+def.String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
 	out.WriteString("fn")
@@ -134,6 +188,9 @@ func (s *String) HashKey() HashKey {
 
 type Builtin struct {
 	Fn BuiltinFunction
+	// Params names the arguments Fn takes, so they can be passed by name.
+	// Fn always receives them by position.
+	Params []string
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -164,8 +221,71 @@ type HashPair struct {
 	Value Object
 }
 
+// Range is the integers from Start up to but not including Stop, counting
+// by Step. Step is never zero. The integers are computed on demand, so
+// large ranges cost no more than small ones.
+type Range struct {
+	Start     int64
+	Stop      int64
+	Step      int64
+	Inclusive bool // Stop itself is in the range if a step lands on it
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	op := ".."
+	if r.Inclusive {
+		op = "..="
+	}
+	if r.Step == 1 {
+		return fmt.Sprintf("%d%s%d", r.Start, op, r.Stop)
+	}
+	return fmt.Sprintf("%d%s%d step %d", r.Start, op, r.Stop, r.Step)
+}
+
+// Len returns the number of integers in the range, or -1 if there are more
+// than an int64 can count.
+func (r *Range) Len() int64 {
+	// the distance between two int64s always fits in a uint64
+	var dist, step uint64
+	switch {
+	case r.Step > 0 && r.Start <= r.Stop:
+		dist, step = uint64(r.Stop)-uint64(r.Start), uint64(r.Step)
+	case r.Step < 0 && r.Start >= r.Stop:
+		dist, step = uint64(r.Start)-uint64(r.Stop), -uint64(r.Step)
+	default:
+		return 0
+	}
+
+	if !r.Inclusive {
+		if dist == 0 {
+			return 0
+		}
+		dist--
+	}
+
+	// the steps after the first integer, plus the first
+	steps := dist / step
+	if steps >= math.MaxInt64 {
+		return -1
+	}
+	return int64(steps) + 1
+}
+
+// At returns the i-th integer of the range.
+func (r *Range) At(i int64) int64 { return r.Start + i*r.Step }
+
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Order []HashKey // the keys of Pairs in the order they were added
+}
+
+// Set adds or replaces the pair stored under key.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.Pairs[key]; !ok {
+		h.Order = append(h.Order, key)
+	}
+	h.Pairs[key] = pair
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -173,7 +293,8 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, key := range h.Order {
+		pair := h.Pairs[key]
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -58,3 +61,90 @@ func TestIntegerHashKey(t *testing.T) {
 		t.Errorf("integers with twoerent content have same hash keys")
 	}
 }
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e-9, "1e-09"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("wrong inspect for %v. expected=%q, got=%q",
+				tt.value, tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestRangeLen(t *testing.T) {
+	tests := []struct {
+		r        Range
+		expected []int64
+	}{
+		{Range{0, 5, 1, false}, []int64{0, 1, 2, 3, 4}},
+		{Range{2, 9, 3, false}, []int64{2, 5, 8}},
+		{Range{5, 0, -2, false}, []int64{5, 3, 1}},
+		{Range{5, 5, 1, false}, []int64{}},
+		{Range{5, 0, 1, false}, []int64{}},
+		{Range{0, 5, -1, false}, []int64{}},
+		{Range{0, 5, 1, true}, []int64{0, 1, 2, 3, 4, 5}},
+		{Range{0, 6, 4, true}, []int64{0, 4}},
+		{Range{5, 5, -1, true}, []int64{5}},
+		{Range{math.MaxInt64 - 2, math.MaxInt64, 1, true},
+			[]int64{math.MaxInt64 - 2, math.MaxInt64 - 1, math.MaxInt64}},
+		{Range{math.MinInt64 + 1, math.MinInt64, -1, true},
+			[]int64{math.MinInt64 + 1, math.MinInt64}},
+		{Range{-2, math.MaxInt64, 1 << 62, false},
+			[]int64{-2, 1<<62 - 2, math.MaxInt64 - 1}},
+		{Range{math.MinInt64, math.MaxInt64, math.MaxInt64, true},
+			[]int64{math.MinInt64, -1, math.MaxInt64 - 1}},
+	}
+
+	for _, tt := range tests {
+		if tt.r.Len() != int64(len(tt.expected)) {
+			t.Errorf("%s: wrong length. expected=%d, got=%d",
+				tt.r.Inspect(), len(tt.expected), tt.r.Len())
+			continue
+		}
+
+		for i, n := range tt.expected {
+			if tt.r.At(int64(i)) != n {
+				t.Errorf("%s: wrong element %d. expected=%d, got=%d",
+					tt.r.Inspect(), i, n, tt.r.At(int64(i)))
+			}
+		}
+	}
+
+	// too many integers to count in an int64
+	for _, r := range []Range{
+		{-5, math.MaxInt64, 1, false},
+		{0, math.MaxInt64, 1, true},
+		{math.MaxInt64, math.MinInt64, -1, true},
+	} {
+		if r.Len() != -1 {
+			t.Errorf("%s: wrong length. expected=-1, got=%d", r.Inspect(), r.Len())
+		}
+	}
+}
+
+func TestHashInspectOrder(t *testing.T) {
+	h := &Hash{Pairs: map[HashKey]HashPair{}}
+
+	for _, key := range []string{"b", "a", "c", "a"} {
+		k := &String{Value: key}
+		h.Set(k.HashKey(), HashPair{Key: k, Value: &Integer{Value: int64(len(h.Order))}})
+	}
+
+	expected := "{b: 0, a: 3, c: 2}"
+	if h.Inspect() != expected {
+		t.Errorf("wrong inspect. expected=%q, got=%q", expected, h.Inspect())
+	}
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"fmt"
+
+	"monkey/token"
+)
+
+// ErrorCode identifies the kind of a ParseError, so tools can tell errors
+// apart without matching on their text.
+type ErrorCode string
+
+const (
+	UnexpectedToken ErrorCode = "unexpected-token" // a specific token was expected
+	IllegalToken    ErrorCode = "illegal-token"    // the lexer reported an error
+	NoPrefixParseFn ErrorCode = "no-prefix-parse-fn"
+	InvalidAssign   ErrorCode = "invalid-assign"
+	OutsideLoop     ErrorCode = "outside-loop" // break or continue outside a loop
+	InvalidPipe     ErrorCode = "invalid-pipe" // the right of |> is not a call
+	InvalidParam    ErrorCode = "invalid-param"
+	InvalidArgument ErrorCode = "invalid-argument" // a positional argument after a named one
+	IntegerOverflow ErrorCode = "integer-overflow"
+	InvalidInteger  ErrorCode = "invalid-integer"
+	InvalidFloat    ErrorCode = "invalid-float"
+)
+
+// ParseError describes a syntax error found by the parser.
+type ParseError struct {
+	Pos      token.Position
+	Code     ErrorCode
+	Expected []token.TokenType // token types that would have been accepted, if known
+	Found    token.Token       // the token the error was reported at
+	Msg      string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
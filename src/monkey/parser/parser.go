@@ -1,36 +1,76 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
 	"monkey/ast"
+	"monkey/lexer"
 	"monkey/token"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = or +=
+	PIPELINE    // |>
+	LOGICALOR   // ||
+	LOGICALAND  // &&
+	BITOR       // |
+	BITXOR      // ^
+	BITAND      // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	RANGE       // .. or ..=
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
-	PREFIX      // -X or !X
+	PREFIX      // -X or !X or ~X
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
 
+// statementKeywords are the tokens that can only start a statement, where
+// the parser can safely resume after an error.
+var statementKeywords = map[token.TokenType]bool{
+	token.LET:      true,
+	token.RETURN:   true,
+	token.WHILE:    true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.FOR:      true,
+}
+
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PIPELINE:        PIPELINE,
+	token.OR:              LOGICALOR,
+	token.AND:             LOGICALAND,
+	token.PIPE:            BITOR,
+	token.CARET:           BITXOR,
+	token.AMPERSAND:       BITAND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.DOTDOT:          RANGE,
+	token.DOTDOT_EQ:       RANGE,
+	token.SHIFT_LEFT:      SHIFT,
+	token.SHIFT_RIGHT:     SHIFT,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
 }
 
 type (
@@ -39,28 +79,43 @@ type (
 )
 
 type Parser struct {
-	tokens *chan token.Token
-	errors []string
+	l      *lexer.Lexer
+	errors []*ParseError
+
+	// every token read, kept for the program when lexing with trivia
+	tokens []token.Token
 
 	curToken  token.Token
 	peekToken token.Token
 
+	// depth counts the braces opened and not yet closed up to curToken
+	depth int
+	// panicking is set by the first error in a statement and cleared once
+	// the parser has skipped to the next one
+	panicking bool
+	// loops counts the loops around curToken within the current function
+	loops int
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
-func New(tokens *chan token.Token) *Parser {
+func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		tokens: tokens,
-		errors: []string{},
+		l:      l,
+		errors: []*ParseError{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.STRING_START, p.parseInterpolatedString)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
@@ -79,6 +134,26 @@ func New(tokens *chan token.Token) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.AMPERSAND, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
+	p.registerInfix(token.CARET, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+
+	p.registerInfix(token.PIPELINE, p.parsePipeExpression)
+	p.registerInfix(token.DOTDOT, p.parseRangeExpression)
+	p.registerInfix(token.DOTDOT_EQ, p.parseRangeExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
@@ -91,7 +166,36 @@ func New(tokens *chan token.Token) *Parser {
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	p.peekToken = <-*p.tokens
+	p.peekToken = p.readToken()
+
+	switch p.curToken.Type {
+	case token.LSQUIRLY:
+		p.depth++
+	case token.RSQUIRLY:
+		p.depth--
+	}
+
+	// comments only matter to tools that read the tokens themselves
+	for p.peekToken.Type == token.COMMENT {
+		p.peekToken = p.readToken()
+	}
+
+	if p.peekTokenIs(token.ILLEGAL) {
+		p.illegalTokenError(p.peekToken)
+	}
+}
+
+func (p *Parser) readToken() token.Token {
+	tok := p.l.NextToken()
+
+	if p.l.Mode()&lexer.ScanTrivia != 0 {
+		n := len(p.tokens)
+		if n == 0 || p.tokens[n-1].Type != token.EOF {
+			p.tokens = append(p.tokens, tok)
+		}
+	}
+
+	return tok
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -112,19 +216,55 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// Errors returns the text of every error found while parsing.
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		msgs[i] = err.Error()
+	}
+	return msgs
+}
+
+// ParseErrors returns every error found while parsing.
+func (p *Parser) ParseErrors() []*ParseError {
 	return p.errors
 }
 
+func (p *Parser) errorAt(tok token.Token, code ErrorCode, expected []token.TokenType,
+	format string, a ...interface{}) {
+	// once a statement has failed, the errors that follow in it are almost
+	// always caused by the first one; lexical errors are real either way
+	if p.panicking && code != IllegalToken {
+		return
+	}
+	p.panicking = true
+
+	p.errors = append(p.errors, &ParseError{
+		Pos:      tok.Pos,
+		Code:     code,
+		Expected: expected,
+		Found:    tok,
+		Msg:      fmt.Sprintf(format, a...),
+	})
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	// the lexer error has already been reported
+	if p.peekTokenIs(token.ILLEGAL) {
+		return
+	}
+
+	p.errorAt(p.peekToken, UnexpectedToken, []token.TokenType{t},
+		"expected next token to be %s, got %s instead", t, p.peekToken.Type)
+}
+
+func (p *Parser) illegalTokenError(tok token.Token) {
+	p.errorAt(tok, IllegalToken, nil, "%s", tok.Literal)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, NoPrefixParseFn, nil,
+		"no prefix parse function for %s found", t)
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -132,22 +272,60 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.EOF) {
+		start := p.curToken
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+		if p.panicking {
+			p.synchronize(start, 0)
+			continue
+		}
 		p.nextToken()
 	}
 
+	program.Tokens = p.tokens
+
 	return program
 }
 
+// synchronize skips the rest of a statement that failed to parse, which
+// began at start, so that one mistake is reported once. It stops after a
+// ';', or on a statement keyword, on the '}' closing the block opened at
+// depth, or at EOF, leaving curToken at the start of the next statement.
+func (p *Parser) synchronize(start token.Token, depth int) {
+	p.panicking = false
+
+	if p.curToken.Pos == start.Pos {
+		p.nextToken()
+	}
+
+	for !p.curTokenIs(token.EOF) {
+		switch {
+		case p.curTokenIs(token.SEMICOLON):
+			p.nextToken()
+			return
+		case p.curTokenIs(token.RSQUIRLY) && p.depth < depth:
+			return
+		case statementKeywords[p.curToken.Type] && p.curToken.Pos != start.Pos:
+			return
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -156,11 +334,19 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
-	}
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LSQUIRLY) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -177,6 +363,99 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parsePattern parses the target of a let statement, starting at the
+// current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LSQUIRLY:
+		return p.parseHashPattern()
+	case token.ILLEGAL:
+		// already reported when it was peeked
+		return nil
+	default:
+		p.errorAt(p.curToken, UnexpectedToken,
+			[]token.TokenType{token.IDENT, token.LBRACKET, token.LSQUIRLY},
+			"expected a name, array pattern or hash pattern, got %s instead", p.curToken.Type)
+		return nil
+	}
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		// the rest element has to come last, so only ']' may follow it
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	pattern.Closing = p.curToken
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RSQUIRLY) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		key := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		pair := ast.HashPatternPair{Key: key, Value: key}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+
+			pair.Value = p.parsePattern()
+			if pair.Value == nil {
+				return nil
+			}
+		}
+
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RSQUIRLY) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RSQUIRLY) {
+		return nil
+	}
+
+	pattern.Closing = p.curToken
+
+	return pattern
+}
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
@@ -191,6 +470,104 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LSQUIRLY) {
+		return nil
+	}
+
+	p.loops++
+	stmt.Body = p.parseBlockStatement()
+	p.loops--
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LSQUIRLY) {
+		return nil
+	}
+
+	p.loops++
+	stmt.Body = p.parseBlockStatement()
+	p.loops--
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseLoopControlStatement parses a break or continue statement.
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	tok := p.curToken
+
+	if p.loops == 0 {
+		p.errorAt(tok, OutsideLoop, nil, "%s outside loop", tok.Literal)
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if tok.Type == token.BREAK {
+		return &ast.BreakStatement{Token: tok}
+	}
+	return &ast.ContinueStatement{Token: tok}
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -241,6 +618,12 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
+// parseIllegal skips a token the lexer could not make sense of. Its error
+// has already been reported when it was read.
+func (p *Parser) parseIllegal() ast.Expression {
+	return nil
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -249,9 +632,29 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		p.errorAt(p.curToken, IntegerOverflow, nil,
+			"integer literal %s overflows int64", p.curToken.Literal)
+		return nil
+	}
+	if err != nil {
+		p.errorAt(p.curToken, InvalidInteger, nil,
+			"could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, InvalidFloat, nil,
+			"could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 
@@ -264,6 +667,31 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{
+		Token:   p.curToken,
+		Strings: []string{p.curToken.Literal},
+	}
+
+	for {
+		p.nextToken()
+		str.Expressions = append(str.Expressions, p.parseExpression(LOWEST))
+
+		if !p.peekTokenIs(token.STRING_MIDDLE) && !p.peekTokenIs(token.STRING_END) {
+			p.peekError(token.STRING_END)
+			return nil
+		}
+
+		p.nextToken()
+		str.Strings = append(str.Strings, p.curToken.Literal)
+
+		if p.curTokenIs(token.STRING_END) {
+			str.Closing = p.curToken
+			return str
+		}
+	}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -291,6 +719,79 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+
+	// the target has already been reported
+	if target == nil {
+		return nil
+	}
+
+	// a target that failed to parse may be missing parts, so the message
+	// must not depend on its String
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.errorAt(p.curToken, InvalidAssign, nil,
+			"left side of %s must be a name or index expression", p.curToken.Literal)
+		return nil
+	}
+
+	// assignment is right-associative: a = b = c is a = (b = c)
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+// parsePipeExpression parses x |> f(y) as the call f(x, y), so that pipes
+// need nothing new from the evaluator and work with macros too.
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	pipe := p.curToken
+
+	p.nextToken()
+	right := p.parseExpression(PIPELINE)
+
+	call, ok := right.(*ast.CallExpression)
+	if !ok {
+		// String would walk into any parts that failed to parse
+		if right != nil {
+			p.errorAt(pipe, InvalidPipe, nil,
+				"right side of |> must be a call, got %s", right.TokenLiteral())
+		}
+		return nil
+	}
+
+	call.Arguments = append([]ast.Expression{left}, call.Arguments...)
+	call.Piped = true
+
+	return call
+}
+
+func (p *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	expression := &ast.RangeExpression{
+		Token:     p.curToken,
+		Start:     start,
+		Inclusive: p.curTokenIs(token.DOTDOT_EQ),
+	}
+
+	p.nextToken()
+	expression.Stop = p.parseExpression(RANGE)
+
+	// step is not a keyword, so it stays usable as a name
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "step" {
+		p.nextToken()
+		p.nextToken()
+		expression.Step = p.parseExpression(RANGE)
+	}
+
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -344,16 +845,25 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	depth := p.depth
+
 	p.nextToken()
 
 	for !p.curTokenIs(token.RSQUIRLY) && !p.curTokenIs(token.EOF) {
+		start := p.curToken
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+		if p.panicking {
+			p.synchronize(start, depth)
+			continue
+		}
 		p.nextToken()
 	}
 
+	block.Closing = p.curToken
+
 	return block
 }
 
@@ -364,7 +874,15 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	// a loop around the function cannot be broken from inside it, nor
+	// from its defaults
+	loops := p.loops
+	p.loops = 0
+	defer func() { p.loops = loops }()
+
+	if !p.parseParameterList(lit) {
+		return nil
+	}
 
 	if !p.expectPeek(token.LSQUIRLY) {
 		return nil
@@ -375,6 +893,52 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
+// parseParameterList parses the parameters of a function literal, which
+// unlike those of a macro may have defaults and end in a ...rest parameter.
+func (p *Parser) parseParameterList(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		// the rest parameter has to come last, so only ')' may follow it
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		lit.Parameters = append(lit.Parameters, ident)
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+
+			if lit.Defaults == nil {
+				lit.Defaults = make(map[string]ast.Expression)
+			}
+			lit.Defaults[ident.Value] = p.parseExpression(LOWEST)
+		} else if len(lit.Defaults) > 0 {
+			// otherwise the arguments could not be matched up by position
+			p.errorAt(ident.Token, InvalidParam, nil,
+				"parameter %s without a default follows a parameter with one", ident.Value)
+			return false
+		}
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return false
+		}
+	}
+
+	return p.expectPeek(token.RPAREN)
+}
+
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := []*ast.Identifier{}
 
@@ -405,6 +969,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Closing = p.curToken
 	return exp
 }
 
@@ -416,13 +981,26 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 		return list
 	}
 
-	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
-
-	for p.peekTokenIs(token.COMMA) {
+	named := false
+	for {
 		p.nextToken()
+
+		// calls can also take name: value arguments, after the positional ones
+		if end == token.RPAREN && p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			named = true
+			list = append(list, p.parseNamedArgument())
+		} else if named {
+			p.errorAt(p.curToken, InvalidArgument, nil,
+				"positional argument follows named argument")
+			return nil
+		} else {
+			list = append(list, p.parseExpression(LOWEST))
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
 	}
 
 	if !p.expectPeek(end) {
@@ -432,24 +1010,70 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+func (p *Parser) parseNamedArgument() ast.Expression {
+	arg := &ast.NamedArgument{
+		Token: p.curToken,
+		Name:  &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal},
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	arg.Value = p.parseExpression(LOWEST)
+
+	return arg
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Closing = p.curToken
 
 	return array
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	tok := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(tok, left, index)
+	}
+
+	exp := &ast.IndexExpression{Token: tok, Left: left, Index: index}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	exp.Closing = p.curToken
+
+	return exp
+}
+
+// parseSliceExpression parses the rest of left[low:high] from the colon.
+func (p *Parser) parseSliceExpression(tok token.Token, left, low ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Low: low}
 
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.High = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 
+	exp.Closing = p.curToken
+
 	return exp
 }
 
@@ -469,6 +1093,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		hash.Pairs[key] = value
+		hash.Keys = append(hash.Keys, key)
 
 		if !p.peekTokenIs(token.RSQUIRLY) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -479,6 +1104,8 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		return nil
 	}
 
+	hash.Closing = p.curToken
+
 	return hash
 }
 
@@ -495,7 +1122,10 @@ func (p *Parser) parseMacroLiteral() ast.Expression {
 		return nil
 	}
 
+	loops := p.loops
+	p.loops = 0
 	lit.Body = p.parseBlockStatement()
+	p.loops = loops
 
 	return lit
 }
@@ -2,10 +2,12 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
 )
 
 func TestLetStatements(t *testing.T) {
@@ -20,8 +22,8 @@ func TestLetStatements(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -53,8 +55,8 @@ func TestReturnStatements(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -81,8 +83,8 @@ func TestReturnStatements(t *testing.T) {
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -112,8 +114,8 @@ func TestIdentifierExpression(t *testing.T) {
 func TestIntegerLiteralExpression(t *testing.T) {
 	input := "5;"
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -140,6 +142,98 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF;", 255},
+		{"0b1010;", 10},
+		{"0o755;", 493},
+		{"1_000_000;", 1000000},
+		{"0x7fff_ffff_ffff_ffff;", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestIntegerLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775808", "1:1: integer literal 9223372036854775808 overflows int64"},
+		{"x + 0x1_0000_0000_0000_0000", "1:5: integer literal 0x1_0000_0000_0000_0000 overflows int64"},
+		{"1__000", `1:1: could not parse "1__000" as integer`},
+		{"1_", `1:1: could not parse "1_" as integer`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"2.5E+3;", 2500},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d",
+				len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -148,6 +242,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"~15;", "~", 15},
 		{"!foobar;", "!", "foobar"},
 		{"-foobar;", "-", "foobar"},
 		{"!true;", "!", true},
@@ -155,8 +250,8 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}
 
 	for _, tt := range prefixTests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -211,11 +306,21 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"true && false", true, "&&", false},
+		{"foobar || barfoo;", "foobar", "||", "barfoo"},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 	}
 
 	for _, tt := range infixTests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -350,11 +455,43 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a || b && c || d",
+			"((a || (b && c)) || d)",
+		},
+		{
+			"a == b && !c || d < e",
+			"(((a == b) && (!c)) || (d < e))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b == c",
+			"(a & (b == c))",
+		},
+		{
+			"a << 1 + b < c >> 2",
+			"((a << (1 + b)) < (c >> 2))",
+		},
+		{
+			"~a & b || c | d",
+			"(((~a) & b) || (c | d))",
+		},
 	}
 
 	for _, tt := range tests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -375,8 +512,8 @@ func TestBooleanExpression(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -405,8 +542,8 @@ func TestBooleanExpression(t *testing.T) {
 func TestIfExpression(t *testing.T) {
 	input := `if (x < y) { x }`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -454,8 +591,8 @@ func TestIfExpression(t *testing.T) {
 func TestIfElseExpression(t *testing.T) {
 	input := `if (x < y) { x } else { y }`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -510,11 +647,155 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { if (x == 3) { continue; } break; x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 3 {
+		t.Fatalf("body is not 3 statements. got=%d\n", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Fatalf("Statements[1] is not ast.BreakStatement. got=%T",
+			stmt.Body.Statements[1])
+	}
+
+	expected := "while(x < y) if(x == 3) continue;break;x"
+	if stmt.String() != expected {
+		t.Errorf("stmt.String() wrong. expected=%q, got=%q", expected, stmt.String())
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedKey      string
+		expectedValue    string
+		expectedIterable string
+		expectedString   string
+	}{
+		{"for (x in xs) { x }", "", "x", "xs", "for(x in xs) x"},
+		{"for (k, v in h) { break; }", "k", "v", "h", "for(k, v in h) break;"},
+		{"for (x in f(1)) { continue }", "", "x", "f(1)", "for(x in f(1)) continue;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if tt.expectedKey == "" {
+			if stmt.Key != nil {
+				t.Errorf("stmt.Key is not nil. got=%s", stmt.Key)
+			}
+		} else if !testIdentifier(t, stmt.Key, tt.expectedKey) {
+			return
+		}
+
+		if !testIdentifier(t, stmt.Value, tt.expectedValue) {
+			return
+		}
+
+		if stmt.Iterable.String() != tt.expectedIterable {
+			t.Errorf("stmt.Iterable wrong. expected=%q, got=%q",
+				tt.expectedIterable, stmt.Iterable.String())
+		}
+
+		if stmt.String() != tt.expectedString {
+			t.Errorf("stmt.String() wrong. expected=%q, got=%q",
+				tt.expectedString, stmt.String())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x xs) { x }", "1:8: expected next token to be IN, got IDENT instead"},
+		{"for (1 in xs) { x }", "1:6: expected next token to be IDENT, got INT instead"},
+		{"for (a, in xs) { x }", "1:9: expected next token to be IDENT, got IN instead"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break outside loop"},
+		{"if (x) { continue }", "1:10: continue outside loop"},
+		{"while (x) { fn() { break; } }", "1:20: break outside loop"},
+		{"while (x) { 1 }; continue;", "1:18: continue outside loop"},
+		{"while (x) { fn(y = if (x) { break; }) { y } }", "1:29: break outside loop"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -568,8 +849,8 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -587,11 +868,171 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestDefaultAndRestParameterParsing(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults map[string]string
+		expectedRest     string
+		expectedString   string
+	}{
+		{"fn(x, y = 10) {}", []string{"x", "y"}, map[string]string{"y": "10"}, "",
+			"fn(x, y = 10) "},
+		{"fn(x = a + 1, y = x) {}", []string{"x", "y"}, map[string]string{"x": "(a + 1)", "y": "x"}, "",
+			"fn(x = (a + 1), y = x) "},
+		{"fn(first, ...others) {}", []string{"first"}, nil, "others",
+			"fn(first, ...others) "},
+		{"fn(...args) {}", []string{}, nil, "args", "fn(...args) "},
+		{"fn(a, b = 2, ...c) {}", []string{"a", "b"}, map[string]string{"b": "2"}, "c",
+			"fn(a, b = 2, ...c) "},
+		{"fn(a, b,) {}", []string{"a", "b"}, nil, "", "fn(a, b) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("%q: length parameters wrong. want %d, got=%d",
+				tt.input, len(tt.expectedParams), len(function.Parameters))
+		}
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Errorf("%q: wrong number of defaults. want %d, got=%d",
+				tt.input, len(tt.expectedDefaults), len(function.Defaults))
+		}
+		for name, expected := range tt.expectedDefaults {
+			def, ok := function.Defaults[name]
+			if !ok {
+				t.Errorf("%q: no default for %s", tt.input, name)
+				continue
+			}
+			if def.String() != expected {
+				t.Errorf("%q: wrong default for %s. want %q, got=%q",
+					tt.input, name, expected, def.String())
+			}
+		}
+
+		switch {
+		case tt.expectedRest == "" && function.Rest != nil:
+			t.Errorf("%q: unexpected rest parameter %s", tt.input, function.Rest)
+		case tt.expectedRest != "" && (function.Rest == nil || function.Rest.Value != tt.expectedRest):
+			t.Errorf("%q: rest parameter is not %s. got=%v", tt.input, tt.expectedRest, function.Rest)
+		}
+
+		if function.String() != tt.expectedString {
+			t.Errorf("expected=%q, got=%q", tt.expectedString, function.String())
+		}
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x = 1, y) {}", "1:11: parameter y without a default follows a parameter with one"},
+		{"fn(...xs, y) {}", "1:9: expected next token to be ), got , instead"},
+		{"fn(...) {}", "1:7: expected next token to be IDENT, got ) instead"},
+		{"fn(1) {}", "1:4: expected next token to be IDENT, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestNamedArgumentParsing(t *testing.T) {
+	l := lexer.New("f(a, b: c, d: [1, 2])")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if len(call.Arguments) != 3 {
+		t.Fatalf("wrong number of arguments. want=3, got=%d", len(call.Arguments))
+	}
+
+	if !testIdentifier(t, call.Arguments[0], "a") {
+		return
+	}
+
+	arg, ok := call.Arguments[1].(*ast.NamedArgument)
+	if !ok {
+		t.Fatalf("call.Arguments[1] is not ast.NamedArgument. got=%T", call.Arguments[1])
+	}
+	if arg.Name.Value != "b" || !testIdentifier(t, arg.Value, "c") {
+		t.Errorf("wrong named argument. got=%s", arg)
+	}
+	if arg.Pos().String() != "1:6" || arg.End().String() != "1:10" {
+		t.Errorf("wrong positions. got=%s-%s", arg.Pos(), arg.End())
+	}
+
+	if call.String() != "f(a, b: c, d: [1, 2])" {
+		t.Errorf("wrong string. got=%q", call.String())
+	}
+
+	l = lexer.New("xs |> f(by: 2)")
+	p = New(l)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	call = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if call.String() != "(xs |> f(by: 2))" {
+		t.Errorf("wrong string. got=%q", call.String())
+	}
+}
+
+func TestInvalidNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(a: 1, 2)", "1:9: positional argument follows named argument"},
+		{"[a: 1]", "1:3: expected next token to be ], got : instead"},
+		{"f(1: 2)", "1:4: expected next token to be ), got : instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -646,11 +1087,21 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 			expectedIdent: "add",
 			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
 		},
+		{
+			input:         "connect(host: \"db\", port: 5432 + 1, tls: true);",
+			expectedIdent: "connect",
+			expectedArgs:  []string{`host: "db"`, "port: (5432 + 1)", "tls: true"},
+		},
+		{
+			input:         "connect(\"db\", tls: !x);",
+			expectedIdent: "connect",
+			expectedArgs:  []string{`"db"`, "tls: (!x)"},
+		},
 	}
 
 	for _, tt := range tests {
-		_, tokens := lexer.New(tt.input)
-		p := New(&tokens)
+		l := lexer.New(tt.input)
+		p := New(l)
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
@@ -682,8 +1133,8 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 func TestStringLiteralExpression(t *testing.T) {
 	input := `"hello world";`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -698,34 +1149,106 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
-func TestParsingEmptyArrayLiterals(t *testing.T) {
-	input := "[]"
+func TestStringLiteralRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world"`, `"hello world"`},
+		{`"she said \"hi\""`, `"she said \"hi\""`},
+		{`"tab\there\\"`, `"tab\there\\"`},
+		{"`line one\nline two`", `"line one\nline two"`},
+		{`"\u{1F600}\u{7}"`, `"😀\u{7}"`},
+	}
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
-			program.Statements[0])
-	}
-	array, ok := stmt.Expression.(*ast.ArrayLiteral)
-	if !ok {
-		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
-	}
+		output := program.String()
+		if output != tt.expected {
+			t.Errorf("wrong output. expected=%q, got=%q", tt.expected, output)
+		}
 
-	if len(array.Elements) != 0 {
-		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
+		l = lexer.New(output)
+		p = New(l)
+		reparsed := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if reparsed.String() != output {
+			t.Errorf("output does not re-parse. expected=%q, got=%q",
+				output, reparsed.String())
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	input := `"Hello ${name}, you have ${len(items) + 1} items"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+
+	expectedStrings := []string{"Hello ", ", you have ", " items"}
+	if len(str.Strings) != len(expectedStrings) {
+		t.Fatalf("wrong number of strings. want=%d, got=%d",
+			len(expectedStrings), len(str.Strings))
+	}
+	for i, s := range expectedStrings {
+		if str.Strings[i] != s {
+			t.Errorf("strings[%d] wrong. want=%q, got=%q", i, s, str.Strings[i])
+		}
+	}
+
+	if len(str.Expressions) != 2 {
+		t.Fatalf("wrong number of expressions. want=2, got=%d",
+			len(str.Expressions))
+	}
+	testIdentifier(t, str.Expressions[0], "name")
+
+	expected := `"Hello ${name}, you have ${(len(items) + 1)} items"`
+	if str.String() != expected {
+		t.Errorf("str.String() wrong. want=%q, got=%q", expected, str.String())
+	}
+}
+
+func TestParsingEmptyArrayLiterals(t *testing.T) {
+	input := "[]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("exp not ast.ArrayLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(array.Elements) != 0 {
+		t.Errorf("len(array.Elements) not 0. got=%d", len(array.Elements))
 	}
 }
 
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -751,8 +1274,8 @@ func TestParsingArrayLiterals(t *testing.T) {
 func TestParsingIndexExpressions(t *testing.T) {
 	input := "myArray[1 + 1]"
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -775,11 +1298,53 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		hasLow   bool
+		hasHigh  bool
+	}{
+		{"xs[1:3]", "(xs[1:3])", true, true},
+		{"xs[:2]", "(xs[:2])", false, true},
+		{"xs[1 + 1:]", "(xs[(1 + 1):])", true, false},
+		{"xs[:]", "(xs[:])", false, false},
+		{"xs[-2:-1]", "(xs[(-2):(-1)])", true, true},
+		{"f(x)[a:b][0]", "((f(x)[a:b])[0])", true, true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+
+		exp := stmt.Expression
+		if index, ok := exp.(*ast.IndexExpression); ok {
+			exp = index.Left
+		}
+
+		slice, ok := exp.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("%q: exp not *ast.SliceExpression. got=%T", tt.input, exp)
+		}
+
+		if (slice.Low != nil) != tt.hasLow || (slice.High != nil) != tt.hasHigh {
+			t.Errorf("%q: wrong bounds. low=%v, high=%v", tt.input, slice.Low, slice.High)
+		}
+	}
+}
+
 func TestParsingEmptyHashLiteral(t *testing.T) {
 	input := "{}"
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -797,8 +1362,8 @@ func TestParsingEmptyHashLiteral(t *testing.T) {
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -825,7 +1390,7 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 			continue
 		}
 
-		expectedValue := expected[literal.String()]
+		expectedValue := expected[literal.Value]
 		testIntegerLiteral(t, value, expectedValue)
 	}
 }
@@ -833,8 +1398,8 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 func TestParsingHashLiteralsBooleanKeys(t *testing.T) {
 	input := `{true: 1, false: 2}`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -868,8 +1433,8 @@ func TestParsingHashLiteralsBooleanKeys(t *testing.T) {
 func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 	input := `{1: 1, 2: 2, 3: 3}`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -905,8 +1470,8 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -939,9 +1504,9 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			continue
 		}
 
-		testFunc, ok := tests[literal.String()]
+		testFunc, ok := tests[literal.Value]
 		if !ok {
-			t.Errorf("No test function for key %q found", literal.String())
+			t.Errorf("No test function for key %q found", literal.Value)
 			continue
 		}
 
@@ -952,8 +1517,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 func TestMacroLiteralParsing(t *testing.T) {
 	input := `macro(x, y) { x + y; }`
 
-	_, tokens := lexer.New(input)
-	p := New(&tokens)
+	l := lexer.New(input)
+	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
@@ -996,6 +1561,676 @@ func TestMacroLiteralParsing(t *testing.T) {
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 }
 
+func TestCommentsAreSkipped(t *testing.T) {
+	input := `// the answer
+let x = /* almost */ 42; // done`
+
+	l := lexer.NewFile("", input, lexer.ScanComments)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	if !testLetStatement(t, program.Statements[0], "x") {
+		return
+	}
+
+	testLiteralExpression(t, program.Statements[0].(*ast.LetStatement).Value, 42)
+}
+
+func TestNodePositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+		expectedEnd string
+	}{
+		{"a + b * c", "1:1", "1:10"},
+		{"let x = fn(a) {\n  a;\n};", "1:1", "3:2"},
+		{"  add(1, 2)", "1:3", "1:12"},
+		{"xs[0]", "1:1", "1:6"},
+		{"-5", "1:1", "1:3"},
+		{"if (x) { 1 } else { 2 }", "1:1", "1:24"},
+		{`{"a": [1, 2]}`, "1:1", "1:14"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0]
+		if stmt.Pos().String() != tt.expectedPos {
+			t.Errorf("%q: wrong pos. expected=%s, got=%s",
+				tt.input, tt.expectedPos, stmt.Pos())
+		}
+		if stmt.End().String() != tt.expectedEnd {
+			t.Errorf("%q: wrong end. expected=%s, got=%s",
+				tt.input, tt.expectedEnd, stmt.End())
+		}
+	}
+}
+
+func TestAssignExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "x = 5"},
+		{"x += y * 2;", "x += (y * 2)"},
+		{"x -= 1", "x -= 1"},
+		{"x *= 2 + 3", "x *= (2 + 3)"},
+		{"x /= a || b", "x /= (a || b)"},
+		{"a = b = c", "a = b = c"},
+		{"f(x = 1)", "f(x = 1)"},
+		{"a[0] = 1", "(a[0]) = 1"},
+		{"h[\"k\"][i + 1] *= 2", "((h[\"k\"])[(i + 1)]) *= 2"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+
+	l := lexer.New("a = b = c")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	assign := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.AssignExpression)
+	if !testIdentifier(t, assign.Target, "a") {
+		return
+	}
+	if _, ok := assign.Value.(*ast.AssignExpression); !ok {
+		t.Errorf("assignment is not right-associative. value=%T", assign.Value)
+	}
+}
+
+func TestDestructuringLetParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs;", "let [a, b] = xs;"},
+		{"let [first, ...rest] = xs;", "let [first, ...rest] = xs;"},
+		{"let [...all] = xs;", "let [...all] = xs;"},
+		{"let [] = xs;", "let [] = xs;"},
+		{"let {name, age: years} = person;", "let {name, age: years} = person;"},
+		{"let {a, b,} = h;", "let {a, b} = h;"},
+		{"let [x, [y, z], {w}] = v;", "let [x, [y, z], {w}] = v;"},
+		{"let {pos: [x, y], size: {w, h}} = box;", "let {pos: [x, y], size: {w, h}} = box;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T",
+				program.Statements[0])
+		}
+
+		if stmt.Name != nil {
+			t.Errorf("%q: stmt.Name should be nil. got=%s", tt.input, stmt.Name)
+		}
+
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+
+	l := lexer.New("let [a, {b: c}, ...d] = xs;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	pattern, ok := stmt.Pattern.(*ast.ArrayPattern)
+	if !ok {
+		t.Fatalf("stmt.Pattern is not ast.ArrayPattern. got=%T", stmt.Pattern)
+	}
+	if len(pattern.Elements) != 2 {
+		t.Fatalf("pattern.Elements has wrong length. got=%d", len(pattern.Elements))
+	}
+	if !testIdentifier(t, pattern.Elements[0].(*ast.Identifier), "a") {
+		return
+	}
+	hash, ok := pattern.Elements[1].(*ast.HashPattern)
+	if !ok {
+		t.Fatalf("pattern.Elements[1] is not ast.HashPattern. got=%T", pattern.Elements[1])
+	}
+	if hash.Pairs[0].Key.Value != "b" || hash.Pairs[0].Value.String() != "c" {
+		t.Errorf("wrong hash pattern pair. got=%s: %s", hash.Pairs[0].Key, hash.Pairs[0].Value)
+	}
+	if pattern.Rest == nil || pattern.Rest.Value != "d" {
+		t.Errorf("pattern.Rest is not d. got=%v", pattern.Rest)
+	}
+	if stmt.Pos().String() != "1:1" || pattern.End().String() != "1:22" {
+		t.Errorf("wrong positions. got=%s-%s", stmt.Pos(), pattern.End())
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, ...b, c] = xs;", "1:13: expected next token to be ], got , instead"},
+		{"let [1] = xs;", "1:6: expected a name, array pattern or hash pattern, got INT instead"},
+		{"let [a b] = xs;", "1:8: expected next token to be ,, got IDENT instead"},
+		{"let [...] = xs;", "1:9: expected next token to be IDENT, got ] instead"},
+		{"let {\"a\": b} = h;", "1:6: expected next token to be IDENT, got STRING instead"},
+		{"let {a: 1} = h;", "1:9: expected a name, array pattern or hash pattern, got INT instead"},
+		{"let [a, b];", "1:11: expected next token to be =, got ; instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestRangeExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		inclusive bool
+		hasStep   bool
+	}{
+		{"0..10", "(0..10)", false, false},
+		{"0..=10", "(0..=10)", true, false},
+		{"a..b step 2", "(a..b step 2)", false, true},
+		{"1 + 1..n * 2 step -1", "((1 + 1)..(n * 2) step (-1))", false, true},
+		{"0..1 << 4", "(0..(1 << 4))", false, false},
+		{"0..n == r", "((0..n) == r)", false, false},
+		{"0..xs[1]", "(0..(xs[1]))", false, false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+
+		rng, ok := stmt.Expression.(*ast.RangeExpression)
+		if !ok {
+			infix, ok := stmt.Expression.(*ast.InfixExpression)
+			if !ok {
+				t.Fatalf("%q: not a range expression. got=%T", tt.input, stmt.Expression)
+			}
+			rng = infix.Left.(*ast.RangeExpression)
+		}
+
+		if rng.Inclusive != tt.inclusive {
+			t.Errorf("%q: Inclusive wrong. expected=%t, got=%t", tt.input, tt.inclusive, rng.Inclusive)
+		}
+
+		if (rng.Step != nil) != tt.hasStep {
+			t.Errorf("%q: Step wrong. got=%v", tt.input, rng.Step)
+		}
+	}
+}
+
+func TestPipeExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		expectedArgs []string
+	}{
+		{"xs |> f()", "(xs |> f())", []string{"xs"}},
+		{"xs |> map(g)", "(xs |> map(g))", []string{"xs", "g"}},
+		{"xs |> map(g) |> sum()", "((xs |> map(g)) |> sum())", []string{"(xs |> map(g))"}},
+		{"a + b |> f(c * d)", "((a + b) |> f((c * d)))", []string{"(a + b)", "(c * d)"}},
+		{"a || b |> f()", "((a || b) |> f())", []string{"(a || b)"}},
+		{"x |> fn(y) { y }()", "(x |> fn(y) y())", []string{"x"}},
+		{"x = y |> f()", "x = (y |> f())", nil},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
+		}
+
+		if tt.expectedArgs == nil {
+			continue
+		}
+
+		call, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("%q: exp not *ast.CallExpression. got=%T", tt.input, stmt.Expression)
+		}
+
+		if len(call.Arguments) != len(tt.expectedArgs) {
+			t.Fatalf("%q: wrong number of arguments. want=%d, got=%d",
+				tt.input, len(tt.expectedArgs), len(call.Arguments))
+		}
+
+		for i, arg := range tt.expectedArgs {
+			if call.Arguments[i].String() != arg {
+				t.Errorf("%q: argument %d wrong. want=%q, got=%q",
+					tt.input, i, arg, call.Arguments[i].String())
+			}
+		}
+
+		if call.Pos() != call.Arguments[0].Pos() {
+			t.Errorf("%q: call should start at the piped value. got=%s", tt.input, call.Pos())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs |> f", "1:4: right side of |> must be a call, got f"},
+		{"xs |> f() + 1", "1:4: right side of |> must be a call, got +"},
+		{"x |> -;", "1:7: no prefix parse function for ; found"},
+		{"x |> @ + 1;", "1:6: unrecognized character in action: U+0040 '@'"},
+		{"xs |> ", "1:7: no prefix parse function for EOF found"},
+	}
+
+	for _, tt := range errorTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2", "1:3: left side of = must be a name or index expression"},
+		{"a + b = 2", "1:7: left side of = must be a name or index expression"},
+		{"f() += 1", "1:5: left side of += must be a name or index expression"},
+		{"@ = 5;", "1:1: unrecognized character in action: U+0040 '@'"},
+		{"99999999999999999999 = 1;", "1:1: integer literal 99999999999999999999 overflows int64"},
+		{"0x += 1", `1:1: bad number syntax: "0x"`},
+		{"[@] = 1;", "1:2: unrecognized character in action: U+0040 '@'"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("%q: expected 1 error, got=%d %q", tt.input, len(errors), errors)
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	input := "let x = 5;\nlet = 10;"
+
+	l := lexer.NewFile("test.mk", input, 0)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors, got none")
+	}
+
+	expected := "test.mk:2:5: expected next token to be IDENT, got = instead"
+	if errors[0] != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedCode     ErrorCode
+		expectedExpected []token.TokenType
+		expectedFound    token.TokenType
+		expectedMessage  string
+	}{
+		{"let = 10;", UnexpectedToken, []token.TokenType{token.IDENT}, token.ASSIGN,
+			"1:5: expected next token to be IDENT, got = instead"},
+		{"let x 10;", UnexpectedToken, []token.TokenType{token.ASSIGN}, token.INT,
+			"1:7: expected next token to be =, got INT instead"},
+		{"*5", NoPrefixParseFn, nil, token.ASTERISK,
+			"1:1: no prefix parse function for * found"},
+		{"9223372036854775808", IntegerOverflow, nil, token.INT,
+			"1:1: integer literal 9223372036854775808 overflows int64"},
+		{"1_", InvalidInteger, nil, token.INT,
+			`1:1: could not parse "1_" as integer`},
+		{"@", IllegalToken, nil, token.ILLEGAL,
+			"1:1: unrecognized character in action: U+0040 '@'"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errs := p.ParseErrors()
+		if len(errs) == 0 {
+			t.Fatalf("%q: expected parser errors, got none", tt.input)
+		}
+
+		err := errs[0]
+		if err.Code != tt.expectedCode {
+			t.Errorf("%q: wrong code. expected=%q, got=%q", tt.input, tt.expectedCode, err.Code)
+		}
+		if fmt.Sprint(err.Expected) != fmt.Sprint(tt.expectedExpected) {
+			t.Errorf("%q: wrong expected types. expected=%v, got=%v",
+				tt.input, tt.expectedExpected, err.Expected)
+		}
+		if err.Found.Type != tt.expectedFound {
+			t.Errorf("%q: wrong found token. expected=%q, got=%q",
+				tt.input, tt.expectedFound, err.Found.Type)
+		}
+		if err.Pos != err.Found.Pos {
+			t.Errorf("%q: position %s is not the found token's %s",
+				tt.input, err.Pos, err.Found.Pos)
+		}
+		if err.Error() != tt.expectedMessage {
+			t.Errorf("%q: wrong message. expected=%q, got=%q",
+				tt.input, tt.expectedMessage, err.Error())
+		}
+		if p.Errors()[0] != tt.expectedMessage {
+			t.Errorf("%q: Errors() should keep the message. got=%q", tt.input, p.Errors()[0])
+		}
+	}
+}
+
+func TestLexerErrorRecovery(t *testing.T) {
+	input := `let a = 5 @ 3;
+let b = 3.14abc;
+let c = "bad \q";
+let e = 10;`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	expected := []string{
+		"1:11: unrecognized character in action: U+0040 '@'",
+		`2:9: bad number syntax: "3.14abc"`,
+		`3:14: invalid escape sequence: \q`,
+	}
+
+	errors := p.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("wrong number of errors. want=%d, got=%d: %q",
+			len(expected), len(errors), errors)
+	}
+
+	for i, msg := range expected {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] wrong. want=%q, got=%q", i, msg, errors[i])
+		}
+	}
+
+	last := program.Statements[len(program.Statements)-1]
+	if !testLetStatement(t, last, "e") {
+		return
+	}
+}
+
+func TestSourceRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"  \n\t// only a comment",
+		`// greet someone
+let greet = fn(name) {   /* be polite */
+	"hello, ${name}!\n"; // escapes stay as written
+};
+
+let raw = ` + "`C:\\path\\${x}`" + `;
+let nums = [0x1F, 0b1_0, 1.5e3, 0o17];
+let h = {"a": 1, true: !false};   
+greet("monkey") // no trailing newline`,
+		"let a = 5 @ 3;\nlet b = 3.14abc;\nlet c = \"bad \\q\";\nlet d = \"open",
+		"/* unterminated",
+	}
+
+	for _, input := range inputs {
+		l := lexer.NewFile("", input, lexer.ScanTrivia)
+		program := New(l).ParseProgram()
+
+		if got := program.Source(); got != input {
+			t.Errorf("source not preserved.\nwant=%q\ngot= %q", input, got)
+		}
+
+		l = lexer.NewReader("", strings.NewReader(input), lexer.ScanComments|lexer.ScanTrivia)
+		program = New(l).ParseProgram()
+
+		if got := program.Source(); got != input {
+			t.Errorf("source not preserved from reader.\nwant=%q\ngot= %q", input, got)
+		}
+	}
+}
+
+func TestSourceWithoutTrivia(t *testing.T) {
+	l := lexer.New("let x = 5;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.Tokens != nil {
+		t.Errorf("program.Tokens should be nil without trivia. got=%d tokens",
+			len(program.Tokens))
+	}
+}
+
+func TestNodeSourceAndReplace(t *testing.T) {
+	input := `let x = add(1,   2); // sum
+let y = x;
+`
+
+	l := lexer.NewFile("", input, lexer.ScanTrivia)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+
+	if got, want := program.NodeSource(let.Value), "add(1,   2)"; got != want {
+		t.Errorf("NodeSource wrong. want=%q, got=%q", want, got)
+	}
+
+	if got, want := program.NodeSource(let), "let x = add(1,   2)"; got != want {
+		t.Errorf("NodeSource wrong. want=%q, got=%q", want, got)
+	}
+
+	out, err := program.Replace(let.Value, "sub(2, 1)")
+	if err != nil {
+		t.Fatalf("Replace returned error: %s", err)
+	}
+
+	expected := `let x = sub(2, 1); // sum
+let y = x;
+`
+	if out != expected {
+		t.Errorf("Replace wrong.\nwant=%q\ngot= %q", expected, out)
+	}
+
+	if _, err := program.Replace(&ast.Identifier{}, "z"); err == nil {
+		t.Errorf("Replace should fail for a node outside the program")
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedLast   string
+	}{
+		{
+			"let = 10;\nlet y = 2;",
+			[]string{"1:5: expected next token to be IDENT, got = instead"},
+			"let y = 2;",
+		},
+		{
+			"let x 5 * 2 + (3;\nlet y = 2;",
+			[]string{"1:7: expected next token to be =, got INT instead"},
+			"let y = 2;",
+		},
+		{
+			"let x = (1 + ;\nlet y = 2;",
+			[]string{"1:14: no prefix parse function for ; found"},
+			"let y = 2;",
+		},
+		{
+			"let x = 5 +\nlet y = 2",
+			[]string{"2:1: no prefix parse function for LET found"},
+			"let y = 2;",
+		},
+		{
+			"let h = {\"a\": };\nlet y = 2;",
+			[]string{"1:15: no prefix parse function for } found"},
+			"let y = 2;",
+		},
+		{
+			"let f = fn() { let = 1; x + ; y };\nlet z = 3;",
+			[]string{
+				"1:20: expected next token to be IDENT, got = instead",
+				"1:29: no prefix parse function for ; found",
+			},
+			"let z = 3;",
+		},
+		{
+			"} let y = 2;",
+			[]string{"1:1: no prefix parse function for } found"},
+			"let y = 2;",
+		},
+		{
+			"let a = 5 @ 3 $ 4;\nlet @d = 1;\nlet y = 2;",
+			[]string{
+				"1:11: unrecognized character in action: U+0040 '@'",
+				"1:15: unrecognized character in action: U+0024 '$'",
+				"2:5: unrecognized character in action: U+0040 '@'",
+			},
+			"let y = 2;",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("%q: wrong number of errors. want=%d, got=%d: %q",
+				tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+
+		for i, msg := range tt.expectedErrors {
+			if errors[i] != msg {
+				t.Errorf("%q: errors[%d] wrong. want=%q, got=%q", tt.input, i, msg, errors[i])
+			}
+		}
+
+		last := program.Statements[len(program.Statements)-1]
+		if last.String() != tt.expectedLast {
+			t.Errorf("%q: last statement wrong. want=%q, got=%q",
+				tt.input, tt.expectedLast, last.String())
+		}
+	}
+}
+
+func TestParserErrorRecoveryInBlock(t *testing.T) {
+	input := `let f = fn(x) {
+	let = x;
+	let y = x * 2;
+	y
+};`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	expected := []string{"2:6: expected next token to be IDENT, got = instead"}
+	errors := p.Errors()
+	if len(errors) != len(expected) || errors[0] != expected[0] {
+		t.Fatalf("wrong errors. want=%q, got=%q", expected, errors)
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+			len(program.Statements))
+	}
+
+	let := program.Statements[0].(*ast.LetStatement)
+	function, ok := let.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("let.Value is not ast.FunctionLiteral. got=%T", let.Value)
+	}
+
+	body := function.Body.Statements
+	if len(body) < 2 {
+		t.Fatalf("function body does not contain the statements after the error. got=%d",
+			len(body))
+	}
+
+	if !testLetStatement(t, body[len(body)-2], "y") {
+		return
+	}
+
+	if body[len(body)-1].String() != "y" {
+		t.Errorf("last statement wrong. want=%q, got=%q", "y", body[len(body)-1].String())
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
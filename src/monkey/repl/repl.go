@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"monkey/diagnostic"
 	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
@@ -17,6 +18,18 @@ func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
 	env := object.NewEnvironment()
 	macroEnv := object.NewEnvironment()
+	color := diagnostic.ColorEnabled(out)
+
+	// every entry is lexed under its own name, so that an error in code
+	// from an earlier entry can quote that entry
+	var entries []string
+	lines := func(filename string) diagnostic.LineFunc {
+		var n int
+		if _, err := fmt.Sscanf(filename, "<repl:%d>", &n); err != nil || n < 1 || n > len(entries) {
+			return func(int) (string, bool) { return "", false }
+		}
+		return diagnostic.StringLines(entries[n-1])
+	}
 
 	for {
 		fmt.Print(out, PROMPT)
@@ -26,12 +39,13 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
-		_, tokens := lexer.New(line)
-		p := parser.New(&tokens)
+		entries = append(entries, line)
+		l := lexer.NewFile(fmt.Sprintf("<repl:%d>", len(entries)), line, 0)
+		p := parser.New(l)
 
 		program := p.ParseProgram()
 		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			printParserErrors(out, p.ParseErrors(), line, color)
 			continue
 		}
 
@@ -39,6 +53,10 @@ func Start(in io.Reader, out io.Writer) {
 		expanded := evaluator.ExpandMacros(program, macroEnv)
 
 		evaluated := evaluator.Eval(expanded, env)
+		if err, ok := evaluated.(*object.Error); ok {
+			diagnostic.FprintLines(out, diagnostic.FromError(err), lines(err.Pos.Filename), color)
+			continue
+		}
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -59,11 +77,10 @@ const MONKEY_FACE = `            __,__
            '-----'
 `
 
-func printParserErrors(out io.Writer, errors []string) {
+func printParserErrors(out io.Writer, errors []*parser.ParseError, source string, color bool) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Woops! We ran into some monkey business here!\n")
-	io.WriteString(out, " parser errors:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "\t"+msg+"\n")
+	for _, err := range errors {
+		diagnostic.Fprint(out, diagnostic.FromParseError(err), source, color)
 	}
 }
@@ -0,0 +1,25 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestErrorQuotesEntryItCameFrom(t *testing.T) {
+	input := "let f = fn(x) { x + true };\nf(1)\n"
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := `error: type mismatch: INTEGER + BOOLEAN
+ --> <repl:1>:1:17
+  |
+1 | let f = fn(x) { x + true };
+  |                 ^^^^^^^^
+`
+	if !strings.Contains(out.String(), expected) {
+		t.Errorf("error does not quote the entry that defined f.\nexpected=\n%s\ngot=\n%s",
+			expected, out.String())
+	}
+}
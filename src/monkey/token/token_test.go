@@ -0,0 +1,21 @@
+package token
+
+import "testing"
+
+func TestPositionString(t *testing.T) {
+	tests := []struct {
+		pos      Position
+		expected string
+	}{
+		{Position{Filename: "test.mk", Line: 3, Column: 7}, "test.mk:3:7"},
+		{Position{Line: 3, Column: 7}, "3:7"},
+		{Position{}, "-"},
+	}
+
+	for _, tt := range tests {
+		if tt.pos.String() != tt.expected {
+			t.Errorf("pos.String() wrong. expected=%q, got=%q",
+				tt.expected, tt.pos.String())
+		}
+	}
+}